@@ -0,0 +1,165 @@
+package statsd
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrQueueFull is returned by an AsyncSender for data dropped because its
+// queue was full. As for ErrRateLimited, a Client counts data dropped this
+// way in the Dropped of Stats, and only reports it to the function set with
+// WithOnError.
+var ErrQueueFull = errors.New("statsd: async queue full")
+
+// AsyncSender provides a non-blocking send interface, queueing data for
+// another Sender that is sent to from a background goroutine.
+type AsyncSender struct {
+	sender  Sender
+	queue   chan []byte
+	dropped uint64
+	// guards closed, so Send never writes to a closed queue
+	mx     sync.RWMutex
+	closed bool
+	abort  chan bool
+	done   chan bool
+	// payloads passed to Send, and those since sent or dropped, guarded by
+	// flushMx for Flush to wait on
+	queued  uint64
+	handled uint64
+	stopped bool
+	flushMx sync.Mutex
+	flushed *sync.Cond
+}
+
+// Send queues a copy of the data, and returns without waiting for it to be
+// sent. If the queue is full, the data is dropped, counted in Dropped, and
+// ErrQueueFull returned.
+func (s *AsyncSender) Send(data []byte) (int, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	if s.closed {
+		return 0, ErrClosed
+	}
+
+	s.flushMx.Lock()
+	s.queued++
+	s.flushMx.Unlock()
+	select {
+	case s.queue <- append([]byte(nil), data...):
+	default:
+		atomic.AddUint64(&s.dropped, 1)
+		s.handle()
+		return 0, ErrQueueFull
+	}
+	return len(data), nil
+}
+
+// handle counts a payload as sent or dropped, waking Flush.
+func (s *AsyncSender) handle() {
+	s.flushMx.Lock()
+	s.handled++
+	s.flushMx.Unlock()
+	s.flushed.Broadcast()
+}
+
+// Flush waits for the data queued before it was called to be sent, then
+// flushes the underlying sender, if it buffers data.
+func (s *AsyncSender) Flush() error {
+	s.flushMx.Lock()
+	for queued := s.queued; s.handled < queued && !s.stopped; {
+		s.flushed.Wait()
+	}
+	s.flushMx.Unlock()
+
+	if f, ok := s.sender.(flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Dropped returns the number of payloads dropped because the queue was full,
+// or could not be drained when closing.
+func (s *AsyncSender) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Close Async Sender
+// As CloseWithTimeout, waiting up to one second for queued data to be sent.
+func (s *AsyncSender) Close() error {
+	return s.CloseWithTimeout(defaultCloseTimeout)
+}
+
+// CloseWithTimeout waits up to timeout for queued data to be sent, then
+// closes the underlying sender. If the timeout is reached, such as when the
+// network is unreachable, data still queued is dropped and ErrCloseTimeout
+// is returned. Later calls return nil, and Send returns ErrClosed once
+// closed.
+func (s *AsyncSender) CloseWithTimeout(timeout time.Duration) error {
+	s.mx.Lock()
+	if s.closed {
+		s.mx.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.queue)
+	s.mx.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-s.done:
+		return s.sender.Close()
+	case <-timer.C:
+	}
+
+	// a send may be stuck, which closing the underlying sender unblocks, so
+	// the background goroutine is not waited for
+	close(s.abort)
+	atomic.AddUint64(&s.dropped, uint64(len(s.queue)))
+	return errors.Join(ErrCloseTimeout, s.sender.Close())
+}
+
+// run sends queued data until the queue is closed and empty, or aborted.
+func (s *AsyncSender) run() {
+	defer close(s.done)
+	defer func() {
+		s.flushMx.Lock()
+		s.stopped = true
+		s.flushMx.Unlock()
+		s.flushed.Broadcast()
+	}()
+	for {
+		select {
+		case data, ok := <-s.queue:
+			if !ok {
+				return
+			}
+			s.sender.Send(data)
+			s.handle()
+		case <-s.abort:
+			return
+		}
+	}
+}
+
+// Returns a new AsyncSender, queueing up to queueSize payloads for sender.
+//
+// If queueSize is 0, defaults to 1024.
+func NewAsyncSender(sender Sender, queueSize int) Sender {
+	if queueSize <= 0 {
+		queueSize = 1024
+	}
+
+	s := &AsyncSender{
+		sender: sender,
+		queue:  make(chan []byte, queueSize),
+		abort:  make(chan bool),
+		done:   make(chan bool),
+	}
+	s.flushed = sync.NewCond(&s.flushMx)
+
+	go s.run()
+	return s
+}
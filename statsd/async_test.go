@@ -0,0 +1,146 @@
+package statsd
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// blockingSender is a Sender whose Send blocks until unblock is closed.
+type blockingSender struct {
+	RecordingSender
+	unblock chan bool
+}
+
+func (s *blockingSender) Send(data []byte) (int, error) {
+	<-s.unblock
+	return s.RecordingSender.Send(data)
+}
+
+func TestAsyncSender(t *testing.T) {
+	rs := NewRecordingSender()
+	s := NewAsyncSender(rs, 10)
+
+	expected := []string{"test.count:1|c", "test.gauge:1|g"}
+	for _, e := range expected {
+		_, err := s.Send([]byte(e))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Close drains the queue
+	err := s.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent := rs.GetSent()
+	if len(sent) != len(expected) {
+		t.Fatalf("got %d payloads expected %d", len(sent), len(expected))
+	}
+	for i, e := range expected {
+		if string(sent[i]) != e {
+			t.Fatalf("got '%s' expected '%s'", sent[i], e)
+		}
+	}
+}
+
+func TestAsyncSenderFlush(t *testing.T) {
+	bs := &blockingSender{unblock: make(chan bool)}
+	s := NewAsyncSender(bs, 10)
+	defer s.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := s.Send([]byte("test.count:1|c")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	flushed := make(chan error)
+	go func() { flushed <- s.(*AsyncSender).Flush() }()
+	select {
+	case <-flushed:
+		t.Fatal("Flush returned before the queue was sent")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(bs.unblock)
+	if err := <-flushed; err != nil {
+		t.Fatal(err)
+	}
+	if n := len(bs.GetSent()); n != 3 {
+		t.Fatalf("got %d sent expected the queue drained by Flush", n)
+	}
+}
+
+func TestClientAsyncFlush(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(NewAsyncSender(newBufferedSender(rs, time.Hour, 1432, 0), 10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.Inc("count", 1, 1.0)
+	if err := c.(*Client).Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if sent := rs.GetSent(); len(sent) != 1 || string(sent[0]) != "count:1|c\n" {
+		t.Fatalf("got '%s' expected the async and buffered senders flushed", sent)
+	}
+}
+
+func TestAsyncSenderDropsWhenFull(t *testing.T) {
+	bs := &blockingSender{unblock: make(chan bool)}
+	s := NewAsyncSender(bs, 1)
+
+	// one payload held by the blocked sender, one queued, the rest dropped
+	full := 0
+	for i := 0; i < 10; i++ {
+		_, err := s.Send([]byte("test.count:1|c"))
+		if errors.Is(err, ErrQueueFull) {
+			full++
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dropped := s.(*AsyncSender).Dropped()
+	if dropped < 8 {
+		t.Fatalf("got %d dropped expected at least 8", dropped)
+	}
+	if uint64(full) != dropped {
+		t.Fatalf("got %d ErrQueueFull expected one per drop, %d", full, dropped)
+	}
+
+	close(bs.unblock)
+	err := s.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uint64(len(bs.GetSent()))+s.(*AsyncSender).Dropped() != 10 {
+		t.Fatalf("sent %d and dropped %d, expected 10 in total", len(bs.GetSent()), s.(*AsyncSender).Dropped())
+	}
+}
+
+func TestAsyncSenderCloseWithTimeout(t *testing.T) {
+	bs := &blockingSender{unblock: make(chan bool)}
+	defer close(bs.unblock)
+	s := NewAsyncSender(bs, 10)
+
+	for i := 0; i < 3; i++ {
+		s.Send([]byte("test.count:1|c"))
+	}
+
+	err := s.(*AsyncSender).CloseWithTimeout(10 * time.Millisecond)
+	if !errors.Is(err, ErrCloseTimeout) {
+		t.Fatalf("got error '%v' expected '%v'", err, ErrCloseTimeout)
+	}
+	if dropped := s.(*AsyncSender).Dropped(); dropped < 2 {
+		t.Fatalf("got %d dropped expected at least 2", dropped)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("second Close got error '%v' expected nil", err)
+	}
+}
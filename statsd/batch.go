@@ -0,0 +1,314 @@
+package statsd
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Batch accumulates metrics from a Client, to be sent together in a single
+// packet by Submit. Each metric is prefixed, sampled and tagged as it would
+// be if sent directly by the client.
+//
+// A Batch is not safe for concurrent use.
+type Batch struct {
+	client  *Client
+	buf     []byte
+	n       uint64
+	maxSize int
+}
+
+// Returns a new, empty Batch for metrics from this client.
+// A batch larger than 1432 bytes, or the size set with WithMaxPacketSize, is
+// an error when submitted, counting the newline set with
+// WithNewlineTerminator.
+func (s *Client) NewBatch() *Batch {
+	maxSize := defaultMaxPacketSize
+	if s != nil && s.maxPacketSize > 0 {
+		maxSize = s.maxPacketSize
+	}
+	if s != nil && s.newline {
+		// leave room for the newline terminating the packet
+		maxSize--
+	}
+	return &Batch{client: s, maxSize: maxSize}
+}
+
+// add formats a metric onto the batch, if it is sampled in.
+func (b *Batch) add(stat string, value []byte, suffix string, rate float32) error {
+	stat, rate, ok, err := b.client.prepare(stat, suffix, rate)
+	if !ok {
+		return err
+	}
+	if b.n > 0 {
+		b.buf = append(b.buf, '\n')
+	}
+	b.buf = b.client.appendMetric(b.buf, stat, value, suffix, rate)
+	b.n++
+	return nil
+}
+
+// Increments a statsd count type.
+// stat is a string name for the metric.
+// value is the integer value
+// rate is the sample rate (0.0 to 1.0)
+func (b *Batch) Inc(stat string, value int64, rate float32) error {
+	var v [20]byte
+	return b.add(stat, strconv.AppendInt(v[:0], value, 10), "|c", rate)
+}
+
+// Decrements a statsd count type.
+// stat is a string name for the metric.
+// value is the integer value.
+// rate is the sample rate (0.0 to 1.0).
+func (b *Batch) Dec(stat string, value int64, rate float32) error {
+	return b.Inc(stat, -value, rate)
+}
+
+// Submits/Updates a statsd gauge type. As with Client.Gauge, a negative value
+// is added as a reset to 0 followed by the value.
+// stat is a string name for the metric.
+// value is the integer value.
+// rate is the sample rate (0.0 to 1.0).
+func (b *Batch) Gauge(stat string, value int64, rate float32) error {
+	var v [20]byte
+	return b.addGauge(stat, strconv.AppendInt(v[:0], value, 10), value < 0, rate)
+}
+
+// Submits/Updates a statsd gauge type with a floating point value.
+// stat is a string name for the metric.
+// value is the float value.
+// rate is the sample rate (0.0 to 1.0).
+func (b *Batch) GaugeFloat(stat string, value float64, rate float32) error {
+	if err := checkFloat(value); err != nil {
+		return err
+	}
+	var v [32]byte
+	return b.addGauge(stat, appendFloat(v[:0], value), value < 0, rate)
+}
+
+// Submits/Updates a statsd gauge type with an unsigned value.
+// stat is a string name for the metric.
+// value is the unsigned integer value.
+// rate is the sample rate (0.0 to 1.0).
+func (b *Batch) GaugeUint64(stat string, value uint64, rate float32) error {
+	var v [20]byte
+	return b.addGauge(stat, strconv.AppendUint(v[:0], value, 10), false, rate)
+}
+
+// Submits/Updates a statsd gauge type with a time, as seconds since the Unix
+// epoch.
+// stat is a string name for the metric.
+// t is the time, sent as t.Unix().
+// rate is the sample rate (0.0 to 1.0).
+func (b *Batch) GaugeTime(stat string, t time.Time, rate float32) error {
+	return b.Gauge(stat, t.Unix(), rate)
+}
+
+// Submits/Updates a statsd gauge type with a time, as milliseconds since the
+// Unix epoch.
+// stat is a string name for the metric.
+// t is the time, sent as t.UnixMilli().
+// rate is the sample rate (0.0 to 1.0).
+func (b *Batch) GaugeTimeMillis(stat string, t time.Time, rate float32) error {
+	return b.Gauge(stat, t.UnixMilli(), rate)
+}
+
+// Submits/Updates a statsd gauge type with the rate per second of count
+// events over window.
+// stat is a string name for the metric.
+// count is the number of events, sent as count / window.Seconds(). A window
+// that is not positive returns ErrInvalidValue.
+// rate is the sample rate (0.0 to 1.0).
+func (b *Batch) RateGauge(stat string, count int64, window time.Duration, rate float32) error {
+	v, err := perSecond(count, window)
+	if err != nil {
+		return err
+	}
+	return b.GaugeFloat(stat, v, rate)
+}
+
+// addGauge adds a gauge, preceded by a reset to 0 if negative.
+func (b *Batch) addGauge(stat string, value []byte, negative bool, rate float32) error {
+	stat, rate, ok, err := b.client.prepare(stat, "|g", rate)
+	if !ok {
+		return err
+	}
+	if b.n > 0 {
+		b.buf = append(b.buf, '\n')
+	}
+	if negative {
+		b.buf = b.client.appendMetric(b.buf, stat, []byte("0"), "|g", rate)
+		b.buf = append(b.buf, '\n')
+	}
+	b.buf = b.client.appendMetric(b.buf, stat, value, "|g", rate)
+	b.n++
+	return nil
+}
+
+// Submits a delta to a statsd gauge.
+// stat is the string name for the metric.
+// value is the (positive or negative) change. A zero change adds nothing,
+// as some servers treat "+0" as setting the gauge to 0.
+// rate is the sample rate (0.0 to 1.0).
+func (b *Batch) GaugeDelta(stat string, value int64, rate float32) error {
+	if value == 0 {
+		return nil
+	}
+	var v [21]byte
+	d := v[:0]
+	if value >= 0 {
+		d = append(d, '+')
+	}
+	return b.add(stat, strconv.AppendInt(d, value, 10), "|g", rate)
+}
+
+// Submits a floating point delta to a statsd gauge.
+// stat is the string name for the metric.
+// value is the (positive or negative) change. A zero change adds nothing,
+// as some servers treat "+0" as setting the gauge to 0.
+// rate is the sample rate (0.0 to 1.0).
+func (b *Batch) GaugeDeltaFloat(stat string, value float64, rate float32) error {
+	if err := checkFloat(value); err != nil {
+		return err
+	}
+	if value == 0 {
+		return nil
+	}
+	var v [33]byte
+	d := v[:0]
+	if value >= 0 {
+		d = append(d, '+')
+	}
+	return b.add(stat, appendFloat(d, value), "|g", rate)
+}
+
+// Submits a statsd timing type.
+// stat is a string name for the metric.
+// delta is the time duration value in milliseconds
+// rate is the sample rate (0.0 to 1.0).
+func (b *Batch) Timing(stat string, delta int64, rate float32) error {
+	var v [20]byte
+	value, ok := b.client.zeroTiming(strconv.AppendInt(v[:0], delta, 10))
+	if !ok {
+		return nil
+	}
+	return b.add(stat, value, "|ms", rate)
+}
+
+// Submits a statsd timing type.
+// stat is a string name for the metric.
+// delta is the timing value as time.Duration
+// rate is the sample rate (0.0 to 1.0).
+func (b *Batch) TimingDuration(stat string, delta time.Duration, rate float32) error {
+	var v [32]byte
+	value, ok := b.client.zeroTiming(b.client.appendDuration(v[:0], delta))
+	if !ok {
+		return nil
+	}
+	return b.add(stat, value, "|ms", rate)
+}
+
+// Submits/Updates a statsd gauge type with a duration in milliseconds.
+// stat is a string name for the metric.
+// delta is the timing value as time.Duration
+// rate is the sample rate (0.0 to 1.0).
+func (b *Batch) TimingGauge(stat string, delta time.Duration, rate float32) error {
+	var v [32]byte
+	return b.addGauge(stat, b.client.appendDuration(v[:0], delta), delta < 0, rate)
+}
+
+// Submits a stats set type.
+// stat is a string name for the metric.
+// value is the string value.
+// rate is the sample rate (0.0 to 1.0).
+func (b *Batch) Set(stat string, value string, rate float32) error {
+	return b.add(stat, []byte(value), "|s", rate)
+}
+
+// Submits a statsd histogram type.
+// stat is a string name for the metric.
+// value is the integer value.
+// rate is the sample rate (0.0 to 1.0).
+func (b *Batch) Histogram(stat string, value int64, rate float32) error {
+	var v [20]byte
+	return b.add(stat, strconv.AppendInt(v[:0], value, 10), "|h", rate)
+}
+
+// Submits a statsd histogram type with a floating point value.
+// stat is a string name for the metric.
+// value is the float value.
+// rate is the sample rate (0.0 to 1.0).
+func (b *Batch) HistogramFloat(stat string, value float64, rate float32) error {
+	if err := checkFloat(value); err != nil {
+		return err
+	}
+	var v [32]byte
+	return b.add(stat, appendFloat(v[:0], value), "|h", rate)
+}
+
+// Submits a DogStatsD distribution type.
+// stat is a string name for the metric.
+// value is the float value.
+// rate is the sample rate (0.0 to 1.0).
+func (b *Batch) Distribution(stat string, value float64, rate float32) error {
+	if err := checkFloat(value); err != nil {
+		return err
+	}
+	var v [32]byte
+	return b.add(stat, appendFloat(v[:0], value), "|d", rate)
+}
+
+// Submits a meter type.
+// stat is a string name for the metric.
+// value is the integer value
+// rate is the sample rate (0.0 to 1.0)
+func (b *Batch) Meter(stat string, value int64, rate float32) error {
+	var v [20]byte
+	return b.add(stat, strconv.AppendInt(v[:0], value, 10), "|m", rate)
+}
+
+// Submits a metric of a type without a method of its own.
+// stat is a string name for the metric.
+// value is the preformatted value string.
+// suffix is the metric type, as for Client.Custom.
+// rate is the sample rate (0.0 to 1.0).
+func (b *Batch) Custom(stat string, value string, suffix string, rate float32) error {
+	if err := checkSuffix(suffix); err != nil {
+		return err
+	}
+	return b.add(stat, []byte(value), "|"+suffix, rate)
+}
+
+// Adds a metric with a preformatted "raw" value string.
+// stat is the string name for the metric.
+// value is a preformatted "raw" value string.
+// rate is the sample rate (0.0 to 1.0).
+func (b *Batch) Raw(stat string, value string, rate float32) error {
+	if b.client != nil && (rate == 0 || rate == UseDefaultRate) {
+		rate = b.client.defaultRateFor(stat, rawType(value), rate)
+	}
+	return b.add(stat, []byte(value), "", rate)
+}
+
+// Len returns the size in bytes of the batch as it would be sent.
+func (b *Batch) Len() int {
+	return len(b.buf)
+}
+
+// Submit sends the batched metrics, separated by newlines, in a single send,
+// and empties the batch so it may be reused. If the batch is larger than the
+// maximum packet size, an error matching ErrPacketTooLarge is returned and
+// nothing is sent; the batch is left as is.
+func (b *Batch) Submit() error {
+	if b.client == nil || b.n == 0 {
+		return nil
+	}
+	if len(b.buf) > b.maxSize {
+		return fmt.Errorf("%w: batch of %d bytes exceeds the maximum of %d bytes", ErrPacketTooLarge, len(b.buf), b.maxSize)
+	}
+	err := b.client.sendMetrics(b.buf, b.n)
+	b.buf = b.buf[:0]
+	b.n = 0
+	return err
+}
@@ -0,0 +1,102 @@
+package statsd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBatch(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(
+		WithSender(rs),
+		WithPrefix("test"),
+		// Float32() of this source is always 0.5
+		WithRandSource(constSource(1<<62)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := c.NewBatch()
+	b.Inc("count", 1, 1.0)
+	b.Gauge("gauge", -5, 1.0)
+	b.TimingDuration("timing", 1500*time.Microsecond, 1.0)
+	b.TimingGauge("lat", 12500*time.Microsecond, 1.0)
+	b.Set("set", "someuser", 0.6)
+	// sampled out
+	b.Inc("sampled", 1, 0.4)
+	if len(rs.GetSent()) != 0 {
+		t.Fatal("batch sent before Submit")
+	}
+
+	err = b.Submit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := strings.Join([]string{
+		"test.count:1|c",
+		"test.gauge:0|g",
+		"test.gauge:-5|g",
+		"test.timing:1.50|ms",
+		"test.lat:12.50|g",
+		"test.set:someuser|s|@0.6",
+	}, "\n")
+	sent := rs.GetSent()
+	if len(sent) != 1 || string(sent[0]) != expected {
+		t.Fatalf("got '%s' expected '%s'", sent, expected)
+	}
+	if b.Len() != 0 {
+		t.Fatal("batch not emptied by Submit")
+	}
+}
+
+func TestBatchTooLarge(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := c.NewBatch()
+	for b.Len() <= defaultMaxPacketSize {
+		b.Inc("count", 1, 1.0)
+	}
+
+	err = b.Submit()
+	if !errors.Is(err, ErrPacketTooLarge) {
+		t.Fatalf("got error '%v' expected '%v'", err, ErrPacketTooLarge)
+	}
+	if len(rs.GetSent()) != 0 {
+		t.Fatal("oversized batch was sent")
+	}
+}
+
+func TestBatchTooLargeNewline(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithMaxPacketSize(8), WithNewlineTerminator(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 8 bytes, and 9 with the newline
+	b := c.NewBatch()
+	b.Inc("abcd", 1, 1.0)
+	if err := b.Submit(); !errors.Is(err, ErrPacketTooLarge) {
+		t.Fatalf("got error '%v' expected '%v'", err, ErrPacketTooLarge)
+	}
+	if len(rs.GetSent()) != 0 {
+		t.Fatal("oversized batch was sent")
+	}
+
+	b = c.NewBatch()
+	b.Inc("abc", 1, 1.0)
+	if err := b.Submit(); err != nil {
+		t.Fatal(err)
+	}
+	if sent := rs.GetSent(); len(sent) != 1 || string(sent[0]) != "abc:1|c\n" {
+		t.Fatalf("got '%q' expected 'abc:1|c\\n'", sent)
+	}
+}
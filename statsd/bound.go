@@ -0,0 +1,93 @@
+package statsd
+
+import "time"
+
+// Counter sends a statsd count type with a stat name and rate bound to it.
+type Counter struct {
+	statter Statter
+	stat    string
+	rate    float32
+}
+
+// Inc increments the counter by n.
+func (c Counter) Inc(n int64) error {
+	return c.statter.Inc(c.stat, n, c.rate)
+}
+
+// Dec decrements the counter by n.
+func (c Counter) Dec(n int64) error {
+	return c.statter.Dec(c.stat, n, c.rate)
+}
+
+// Returns a Counter sending stat at rate via statter. It is for Statter
+// implementations; callers use the Counter method of a Statter.
+func NewCounter(statter Statter, stat string, rate float32) Counter {
+	return Counter{statter: statter, stat: stat, rate: rate}
+}
+
+// Gauge sends a statsd gauge type with a stat name and rate bound to it.
+type Gauge struct {
+	statter Statter
+	stat    string
+	rate    float32
+}
+
+// Set sets the gauge to value.
+func (g Gauge) Set(value int64) error {
+	return g.statter.Gauge(g.stat, value, g.rate)
+}
+
+// SetFloat sets the gauge to a floating point value.
+func (g Gauge) SetFloat(value float64) error {
+	return g.statter.GaugeFloat(g.stat, value, g.rate)
+}
+
+// Add changes the gauge by delta. A zero delta sends nothing.
+func (g Gauge) Add(delta int64) error {
+	return g.statter.GaugeDelta(g.stat, delta, g.rate)
+}
+
+// Returns a Gauge sending stat at rate via statter. It is for Statter
+// implementations; callers use the BoundGauge method of a Statter.
+func NewGauge(statter Statter, stat string, rate float32) Gauge {
+	return Gauge{statter: statter, stat: stat, rate: rate}
+}
+
+// Timer sends a statsd timing type with a stat name and rate bound to it.
+type Timer struct {
+	statter Statter
+	stat    string
+	rate    float32
+}
+
+// Record sends the duration d.
+func (t Timer) Record(d time.Duration) error {
+	return t.statter.TimingDuration(t.stat, d, t.rate)
+}
+
+// Time calls f, and sends its duration.
+func (t Timer) Time(f func()) error {
+	return t.statter.Time(t.stat, t.rate, f)
+}
+
+// Returns a Timer sending stat at rate via statter. It is for Statter
+// implementations; callers use the Timer method of a Statter.
+func NewTimer(statter Statter, stat string, rate float32) Timer {
+	return Timer{statter: statter, stat: stat, rate: rate}
+}
+
+// Counter returns a Counter that sends stat at rate with this client, so
+// that the name is only written once.
+func (s *Client) Counter(stat string, rate float32) Counter {
+	return NewCounter(s, stat, rate)
+}
+
+// BoundGauge returns a Gauge that sends stat at rate with this client.
+func (s *Client) BoundGauge(stat string, rate float32) Gauge {
+	return NewGauge(s, stat, rate)
+}
+
+// Timer returns a Timer that sends stat at rate with this client.
+func (s *Client) Timer(stat string, rate float32) Timer {
+	return NewTimer(s, stat, rate)
+}
@@ -0,0 +1,42 @@
+package statsd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClientBoundMetrics(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithPrefix("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	counter := c.Counter("requests", 1.0)
+	counter.Inc(2)
+	counter.Dec(1)
+	gauge := c.BoundGauge("depth", 1.0)
+	gauge.Set(3)
+	gauge.SetFloat(0.5)
+	gauge.Add(-1)
+	c.Timer("latency", 0.999999).Record(1500 * time.Microsecond)
+
+	expected := []string{
+		"test.requests:2|c",
+		"test.requests:-1|c",
+		"test.depth:3|g",
+		"test.depth:0.5|g",
+		"test.depth:-1|g",
+		"test.latency:1.50|ms|@0.999999",
+	}
+	sent := rs.GetSent()
+	if len(sent) != len(expected) {
+		t.Fatalf("got '%s' expected %q", sent, expected)
+	}
+	for i, e := range expected {
+		if string(sent[i]) != e {
+			t.Fatalf("got '%s' expected '%s'", sent[i], e)
+		}
+	}
+}
@@ -2,62 +2,187 @@ package statsd
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// defaultMaxPacketSize is the largest udp packet considered safe for local
+// traffic.
+// https://github.com/etsy/statsd/blob/master/docs/metric_types.md#multi-metric-packets
+const defaultMaxPacketSize = 1432
+
 // BufferedSender provides a buffered statsd udp, sending multiple
 // metrics, where possible.
 type BufferedSender struct {
 	flushBytes    int
 	flushInterval time.Duration
+	jitter        float64
 	sender        Sender
 	buffer        *bytes.Buffer
-	reqs          chan []byte
+	mx            sync.Mutex
+	closed        bool
+	closing       int32
 	shutdown      chan bool
 }
 
-// Send bytes
+// Send appends data to the buffer, flushing first if the addition would
+// exceed flushBytes. Data that is larger than flushBytes on its own is sent
+// by itself, rather than being dropped.
 func (s *BufferedSender) Send(data []byte) (int, error) {
-	s.reqs <- data
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.closed {
+		return 0, ErrClosed
+	}
+
+	// StatsD supports receiving multiple metrics in a single packet by
+	// separating them with a newline.
+	if s.buffer.Len() > 0 && s.buffer.Len()+len(data)+1 > s.flushBytes {
+		if _, err := s.flush(); err != nil {
+			return 0, err
+		}
+	}
+
+	if len(data)+1 > s.flushBytes {
+		return s.sender.Send(data)
+	}
+
+	s.buffer.Write(data)
+	s.buffer.WriteByte('\n')
+	if s.buffer.Len() >= s.flushBytes {
+		if _, err := s.flush(); err != nil {
+			return 0, err
+		}
+	}
 	return len(data), nil
 }
 
+// RemoteAddr returns the address the underlying sender sends to.
+func (s *BufferedSender) RemoteAddr() net.Addr {
+	if a, ok := s.sender.(Addressable); ok {
+		return a.RemoteAddr()
+	}
+	return nil
+}
+
+// Reconnect reconnects the underlying sender, if it implements Reconnecter.
+func (s *BufferedSender) Reconnect() error {
+	if r, ok := s.sender.(Reconnecter); ok {
+		return r.Reconnect()
+	}
+	return nil
+}
+
+// Flush sends any pending data synchronously.
+func (s *BufferedSender) Flush() error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	if s.buffer.Len() == 0 {
+		return nil
+	}
+	_, err := s.flush()
+	return err
+}
+
 // Close Buffered Sender
+// As CloseWithTimeout, waiting up to one second for pending data to be sent.
 func (s *BufferedSender) Close() error {
+	return s.CloseWithTimeout(defaultCloseTimeout)
+}
+
+// CloseWithTimeout stops the flush loop, sends any pending data, and closes
+// the underlying sender, waiting up to timeout for this to finish. If the
+// timeout is reached, such as when a send is stuck, the underlying sender is
+// closed to abandon it and ErrCloseTimeout is returned. Later calls return
+// nil, and Send returns ErrClosed once closed.
+func (s *BufferedSender) CloseWithTimeout(timeout time.Duration) error {
+	if !atomic.CompareAndSwapInt32(&s.closing, 0, 1) {
+		return nil
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.close()
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return errors.Join(ErrCloseTimeout, s.sender.Close())
+	}
+}
+
+// close stops the flush loop, sends any pending data, and closes the
+// underlying sender.
+func (s *BufferedSender) close() error {
+	s.mx.Lock()
+	s.closed = true
+	s.mx.Unlock()
+
 	s.shutdown <- true
-	err := s.sender.Close()
-	return err
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.buffer.Len() > 0 {
+		s.flush()
+	}
+	return s.sender.Close()
 }
 
 // Start Buffered Sender
-// Begins ticker and read loop
+// Begins timer and flush loop
 func (s *BufferedSender) Start() {
-	ticker := time.NewTicker(s.flushInterval)
+	timer := time.NewTimer(s.firstInterval())
+	defer timer.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
+		case <-timer.C:
+			s.mx.Lock()
 			if s.buffer.Len() > 0 {
 				s.flush()
 			}
-		case req := <-s.reqs:
-			// StatsD supports receiving multiple metrics in a single packet by
-			// separating them with a newline.
-			newLine := append(req, '\n')
-			if s.buffer.Len()+len(newLine) > s.flushBytes {
-				s.flush()
-			}
-			s.buffer.Write(newLine)
-			if s.buffer.Len() >= s.flushBytes {
-				s.flush()
-			}
+			s.mx.Unlock()
+			timer.Reset(s.nextInterval())
 		case <-s.shutdown:
-			break
+			return
 		}
 	}
 }
 
-// flush the buffer/send to remove endpoint.
+// firstInterval returns the time until the first flush: flushInterval, or
+// with jitter, a random time within it, so that senders started together do
+// not flush together.
+func (s *BufferedSender) firstInterval() time.Duration {
+	if s.jitter == 0 {
+		return s.flushInterval
+	}
+	return time.Duration(rand.Int63n(int64(s.flushInterval)))
+}
+
+// nextInterval returns the time until the next flush: flushInterval, varied
+// randomly by up to the jitter fraction either way.
+func (s *BufferedSender) nextInterval() time.Duration {
+	if s.jitter == 0 {
+		return s.flushInterval
+	}
+	f := 1 + s.jitter*(2*rand.Float64()-1)
+	return time.Duration(float64(s.flushInterval) * f)
+}
+
+// flush the buffer/send to remote endpoint.
+// Must be called with the mutex held.
 func (s *BufferedSender) flush() (int, error) {
 	n, err := s.sender.Send(s.buffer.Bytes())
 	s.buffer.Reset() // clear the buffer
@@ -75,24 +200,56 @@ func (s *BufferedSender) flush() (int, error) {
 //
 // flushBytes specifies the maximum udp packet size you wish to send. If adding
 // a metric would result in a larger packet than flushBytes, the packet will
-// first be send, then the new data will be added to the next packet.
+// first be send, then the new data will be added to the next packet. A single
+// metric larger than flushBytes is sent in a packet of its own.
+//
+// If flushBytes is 0, defaults to 1432 bytes. If flushInterval is 0, defaults
+// to 300ms.
 func NewBufferedSender(addr string, flushInterval time.Duration, flushBytes int) (Sender, error) {
+	return NewBufferedSenderWithJitter(addr, flushInterval, flushBytes, 0)
+}
+
+// Returns a new BufferedSender, as NewBufferedSender, with jittered flushes
+// to avoid many senders started at the same time flushing in step, and
+// loading the server in periodic spikes. The first flush happens at a random
+// time within flushInterval, and each interval after that is varied randomly
+// by up to the jitter fraction of flushInterval either way, such as 0.1 for
+// ±10%.
+//
+// A jitter of 0 disables jitter, as with NewBufferedSender. It must be less
+// than 1.
+func NewBufferedSenderWithJitter(addr string, flushInterval time.Duration, flushBytes int, jitter float64) (Sender, error) {
+	if jitter < 0 || jitter >= 1 {
+		return nil, fmt.Errorf("Invalid jitter %v, must be at least 0 and less than 1", jitter)
+	}
+	if flushBytes <= 0 {
+		flushBytes = defaultMaxPacketSize
+	}
+	if flushInterval <= time.Duration(0) {
+		flushInterval = 300 * time.Millisecond
+	}
+
 	simpleSender, err := NewSimpleSender(addr)
 	if err != nil {
 		return nil, err
 	}
+	return newBufferedSender(simpleSender, flushInterval, flushBytes, jitter), nil
+}
 
-	sender := &BufferedSender{
+// newBufferedSender returns a started BufferedSender buffering data for
+// sender.
+func newBufferedSender(sender Sender, flushInterval time.Duration, flushBytes int, jitter float64) *BufferedSender {
+	s := &BufferedSender{
 		flushBytes:    flushBytes,
 		flushInterval: flushInterval,
-		sender:        simpleSender,
+		jitter:        jitter,
+		sender:        sender,
 		buffer:        bytes.NewBuffer(make([]byte, 0, flushBytes)),
-		reqs:          make(chan []byte),
 		shutdown:      make(chan bool),
 	}
 
-	go sender.Start()
-	return sender, nil
+	go s.Start()
+	return s
 }
 
 // Return a new BufferedClient
@@ -114,22 +271,12 @@ func NewBufferedSender(addr string, flushInterval time.Duration, flushBytes int)
 // for local traffic. If sending over the public internet, 512 bytes is
 // the recommended value.
 func NewBufferedClient(addr, prefix string, flushInterval time.Duration, flushBytes int) (Statter, error) {
-	if flushBytes <= 0 {
-		// https://github.com/etsy/statsd/blob/master/docs/metric_types.md#multi-metric-packets
-		flushBytes = 1432
-	}
-	if flushInterval <= time.Duration(0) {
-		flushInterval = 300 * time.Millisecond
-	}
 	sender, err := NewBufferedSender(addr, flushInterval, flushBytes)
 	if err != nil {
 		return nil, err
 	}
 
-	client := &Client{
-		prefix: prefix,
-		sender: sender,
-	}
+	client := newClient(sender, prefix)
 
 	return client, nil
 }
@@ -2,6 +2,7 @@ package statsd
 
 import (
 	"bytes"
+	"errors"
 	"log"
 	"reflect"
 	"testing"
@@ -33,6 +34,7 @@ func TestBufferedClient(t *testing.T) {
 		}
 
 		data := make([]byte, 128)
+		l.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
 		_, _, err = l.ReadFrom(data)
 		if err != nil {
 			c.Close()
@@ -108,6 +110,42 @@ func TestBufferedClient2(t *testing.T) {
 	}
 }
 
+func TestBufferedClientFlush(t *testing.T) {
+	l, err := newUDPListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	c, err := NewBufferedClient(l.LocalAddr().String(), "test", time.Hour, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for i := 0; i < 3; i++ {
+		err = c.Inc("count", 1, 1.0)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = c.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := make([]byte, 1024)
+	n, _, err := l.ReadFrom(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "test.count:1|c\ntest.count:1|c\ntest.count:1|c\n"
+	if string(data[:n]) != expected {
+		t.Fatalf("got '%s' expected '%s'", data[:n], expected)
+	}
+}
+
 func ExampleClient_buffered() {
 	// first create a client
 	client, err := NewBufferedClient("127.0.0.1:8125", "test-client", 10*time.Millisecond, 0)
@@ -125,3 +163,152 @@ func ExampleClient_buffered() {
 		log.Printf("Error sending metric: %+v", err)
 	}
 }
+
+func TestBufferedSenderCloseFlushes(t *testing.T) {
+	l, err := newUDPListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	s, err := NewBufferedSender(l.LocalAddr().String(), time.Hour, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.Send([]byte("test.count:1|c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := make([]byte, 1024)
+	n, _, err := l.ReadFrom(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "test.count:1|c\n"
+	if string(data[:n]) != expected {
+		t.Fatalf("got '%s' expected '%s'", data[:n], expected)
+	}
+}
+
+func TestBufferedSenderOversized(t *testing.T) {
+	l, err := newUDPListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	s, err := NewBufferedSender(l.LocalAddr().String(), time.Hour, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	big := "test.a.very.long.stat.name:1|c"
+	for _, d := range []string{"a:1|c", big} {
+		_, err = s.Send([]byte(d))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, expected := range []string{"a:1|c\n", big} {
+		data := make([]byte, 1024)
+		n, _, err := l.ReadFrom(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data[:n]) != expected {
+			t.Fatalf("got '%s' expected '%s'", data[:n], expected)
+		}
+	}
+}
+
+func TestBufferedSenderCloseWithTimeout(t *testing.T) {
+	bs := &blockingSender{unblock: make(chan bool)}
+	defer close(bs.unblock)
+	s := &BufferedSender{
+		flushBytes:    1024,
+		flushInterval: time.Hour,
+		sender:        bs,
+		buffer:        bytes.NewBuffer(make([]byte, 0, 1024)),
+		shutdown:      make(chan bool),
+	}
+	go s.Start()
+
+	s.Send([]byte("test.count:1|c"))
+	// the flush blocks, holding the buffer
+	go s.Flush()
+
+	err := s.CloseWithTimeout(10 * time.Millisecond)
+	if !errors.Is(err, ErrCloseTimeout) {
+		t.Fatalf("got error '%v' expected '%v'", err, ErrCloseTimeout)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("second Close got error '%v' expected nil", err)
+	}
+}
+
+func TestBufferedSenderJitter(t *testing.T) {
+	interval := 100 * time.Millisecond
+	s := &BufferedSender{flushInterval: interval}
+	if d := s.firstInterval(); d != interval {
+		t.Fatalf("got first interval %v expected %v", d, interval)
+	}
+	if d := s.nextInterval(); d != interval {
+		t.Fatalf("got interval %v expected %v", d, interval)
+	}
+
+	s.jitter = 0.1
+	for i := 0; i < 100; i++ {
+		if d := s.firstInterval(); d < 0 || d >= interval {
+			t.Fatalf("got first interval %v expected less than %v", d, interval)
+		}
+		if d := s.nextInterval(); d < 90*time.Millisecond || d > 110*time.Millisecond {
+			t.Fatalf("got interval %v expected within 10%% of %v", d, interval)
+		}
+	}
+
+	for _, jitter := range []float64{-0.1, 1} {
+		_, err := NewBufferedSenderWithJitter("127.0.0.1:8125", interval, 0, jitter)
+		if err == nil {
+			t.Fatalf("expected an error for a jitter of %v", jitter)
+		}
+	}
+}
+
+func TestBufferedSenderWithJitterFlushes(t *testing.T) {
+	l, err := newUDPListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	s, err := NewBufferedSenderWithJitter(l.LocalAddr().String(), 20*time.Millisecond, 0, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for i := 0; i < 2; i++ {
+		_, err = s.Send([]byte("test.count:1|c"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data := make([]byte, 128)
+		n, _, err := l.ReadFrom(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data[:n]) != "test.count:1|c\n" {
+			t.Fatalf("got '%s' expected 'test.count:1|c\\n'", data[:n])
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package statsd
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestClientMethodsAfterClose(t *testing.T) {
+	c, err := NewClientWithOptions(WithSender(NewRecordingSender()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	for _, tt := range statsdPacketTests {
+		method := reflect.ValueOf(c).MethodByName(tt.Method)
+		e := method.Call([]reflect.Value{
+			reflect.ValueOf(tt.Stat),
+			reflect.ValueOf(tt.Value),
+			reflect.ValueOf(tt.Rate)})[0]
+		err, _ := e.Interface().(error)
+		if !errors.Is(err, ErrClosed) {
+			t.Fatalf("%s got error '%v' expected '%v'", tt.Method, err, ErrClosed)
+		}
+	}
+
+	for name, f := range map[string]func() error{
+		"Raw":   func() error { return c.Raw("raw", "1|c", 1.0) },
+		"Flush": c.Flush,
+		"Batch": func() error { return c.NewBatch().Inc("count", 1, 1.0) },
+	} {
+		if err := f(); !errors.Is(err, ErrClosed) {
+			t.Fatalf("%s got error '%v' expected '%v'", name, err, ErrClosed)
+		}
+	}
+}
+
+func TestSendersAfterClose(t *testing.T) {
+	l, err := newUDPListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	addr := l.LocalAddr().String()
+
+	tl, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Close()
+
+	dir, err := os.MkdirTemp("", "statsd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "statsd.sock")
+	ul, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ul.Close()
+
+	resolveUDPAddr = func(network, addr string) (*net.UDPAddr, error) {
+		return l.LocalAddr().(*net.UDPAddr), nil
+	}
+	defer func() { resolveUDPAddr = net.ResolveUDPAddr }()
+
+	senders := map[string]func() (Sender, error){
+		"SimpleSender":   func() (Sender, error) { return NewSimpleSender(addr) },
+		"BufferedSender": func() (Sender, error) { return NewBufferedSender(addr, time.Hour, 0) },
+		"TCPSender":      func() (Sender, error) { return NewTCPSender(tl.Addr().String()) },
+		"UnixgramSender": func() (Sender, error) { return NewUnixgramSender(path) },
+		"ResolvingSimpleSender": func() (Sender, error) {
+			return NewResolvingSimpleSender("statsd.example.com:8125", time.Hour)
+		},
+		"AsyncSender": func() (Sender, error) {
+			return NewAsyncSender(NewRecordingSender(), 0), nil
+		},
+		"FailoverSender": func() (Sender, error) {
+			return NewFailoverSender([]string{addr})
+		},
+		"WriterSender": func() (Sender, error) {
+			return NewWriterSender(&bytes.Buffer{}), nil
+		},
+		"RateLimitedSender": func() (Sender, error) {
+			return NewRateLimitedSender(NewRecordingSender(), 10)
+		},
+		"PooledSender": func() (Sender, error) {
+			return NewPooledSender(addr, 2)
+		},
+		"HTTPSender": func() (Sender, error) {
+			return NewHTTPSender("http://127.0.0.1:1/", nil)
+		},
+		"SenderFromConn": func() (Sender, error) {
+			c, err := net.ListenPacket("udp", "127.0.0.1:0")
+			if err != nil {
+				return nil, err
+			}
+			return NewSenderFromConn(c, addr)
+		},
+		"SenderOwningConn": func() (Sender, error) {
+			c, err := net.ListenPacket("udp", "127.0.0.1:0")
+			if err != nil {
+				return nil, err
+			}
+			return NewSenderOwningConn(c, addr)
+		},
+		"MultiSender": func() (Sender, error) {
+			return NewMultiSender(NewRecordingSender(), NewRecordingSender()), nil
+		},
+		"repeatSender": func() (Sender, error) {
+			return newRepeatSender(NewRecordingSender(), time.Hour), nil
+		},
+	}
+
+	for name, newSender := range senders {
+		s, err := newSender()
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if err := s.Close(); err != nil {
+			t.Fatalf("%s Close got error '%v'", name, err)
+		}
+		if err := s.Close(); err != nil {
+			t.Fatalf("%s second Close got error '%v' expected nil", name, err)
+		}
+		_, err = s.Send([]byte("test.count:1|c"))
+		if !errors.Is(err, ErrClosed) {
+			t.Fatalf("%s Send got error '%v' expected '%v'", name, err, ErrClosed)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package statsd
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// counterTotal is the sum of the sampled out values of a counter, and the
+// client to send it with.
+type counterTotal struct {
+	client *Client
+	stat   string
+	total  int64
+}
+
+// counterTotals accumulates sampled out counters for WithSampleRateGuarantee,
+// sending them every interval until stopped.
+type counterTotals struct {
+	mx      sync.Mutex
+	pending map[string]*counterTotal
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func newCounterTotals() *counterTotals {
+	return &counterTotals{
+		pending: make(map[string]*counterTotal),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// start sends the pending totals every interval, until stop is called.
+func (c *counterTotals) start(interval time.Duration) {
+	go func() {
+		defer close(c.stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.flush()
+			case <-c.done:
+				return
+			}
+		}
+	}()
+}
+
+// stop stops sending pending totals, and waits for a send in progress.
+func (c *counterTotals) stop() {
+	close(c.done)
+	<-c.stopped
+}
+
+// add adds value to the pending total for key.
+func (c *counterTotals) add(key string, client *Client, stat string, value int64) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if t, ok := c.pending[key]; ok {
+		t.total += value
+		return
+	}
+	c.pending[key] = &counterTotal{client: client, stat: stat, total: value}
+}
+
+// take removes and returns the pending total for key.
+func (c *counterTotals) take(key string) int64 {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	t, ok := c.pending[key]
+	if !ok {
+		return 0
+	}
+	delete(c.pending, key)
+	return t.total
+}
+
+// flush sends every pending total that is not 0, joining any errors
+// together.
+func (c *counterTotals) flush() error {
+	c.mx.Lock()
+	pending := c.pending
+	c.pending = make(map[string]*counterTotal)
+	c.mx.Unlock()
+
+	var errs []error
+	for _, t := range pending {
+		if t.total == 0 {
+			continue
+		}
+		if err := t.client.sendCount(t.stat, t.total); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// submitCount submits a counter for WithSampleRateGuarantee. A sampled out
+// value is added to the pending total of the stat, which is sent with the
+// next sampled in value or after the interval.
+func (s *Client) submitCount(stat string, value int64, rate float32) error {
+	stat, rate, ok, err := s.check(stat, "|c", rate)
+	if !ok {
+		return err
+	}
+
+	key := s.getPrefix() + s.separator + stat + s.tagString
+	if !s.sampled(stat, rate) {
+		atomic.AddUint64(&s.stats.sampledOut, 1)
+		s.counts.add(key, s, stat, value)
+		return nil
+	}
+	return s.sendCount(stat, value+s.counts.take(key))
+}
+
+// sendCount sends an exact count for stat, which has already been checked,
+// without a rate.
+func (s *Client) sendCount(stat string, total int64) error {
+	var b [20]byte
+	bp := bufPool.Get().(*[]byte)
+	buf := s.appendMetric((*bp)[:0], stat, strconv.AppendInt(b[:0], total, 10), "|c", 1)
+	err := s.sendMetrics(buf, 1)
+	*bp = buf
+	bufPool.Put(bp)
+	return err
+}
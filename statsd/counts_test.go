@@ -0,0 +1,89 @@
+package statsd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClientSampleRateGuarantee(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(
+		WithSender(rs),
+		WithPrefix("test"),
+		WithSampleRateGuarantee(time.Hour),
+		// Float32() of this source is always 0.5
+		WithRandSource(constSource(1<<62)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// sampled out, and added up
+	c.Inc("count", 2, 0.5)
+	c.Dec("count", 1, 0.5)
+	c.NewSubStatter("sub").Inc("count", 4, 0.5)
+	c.Inc("rare", 1, 0.1)
+	if sent := rs.GetSent(); len(sent) != 0 {
+		t.Fatalf("got '%s' expected nothing sent", sent)
+	}
+	if stats := c.Stats(); stats.SampledOut != 4 {
+		t.Fatalf("got %d sampled out expected 4", stats.SampledOut)
+	}
+
+	// sampled in, with the pending total
+	c.Inc("count", 3, 0.6)
+	sent := rs.GetSent()
+	if len(sent) != 1 || string(sent[0]) != "test.count:4|c" {
+		t.Fatalf("got '%s' expected 'test.count:4|c'", sent)
+	}
+
+	rs.Clear()
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]bool{"test.sub.count:4|c": true, "test.rare:1|c": true}
+	sent = rs.GetSent()
+	if len(sent) != len(expected) {
+		t.Fatalf("got '%s' expected %d totals", sent, len(expected))
+	}
+	for _, s := range sent {
+		if !expected[string(s)] {
+			t.Fatalf("got '%s' expected one of %v", s, expected)
+		}
+	}
+}
+
+func TestClientSampleRateGuaranteeInterval(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(
+		WithSender(rs),
+		WithSampleRateGuarantee(10*time.Millisecond),
+		// Float32() of this source is always 0.5
+		WithRandSource(constSource(1<<62)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Inc("count", 1, 0.1)
+	deadline := time.Now().Add(time.Second)
+	for len(rs.GetSent()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	sent := rs.GetSent()
+	if len(sent) != 1 || string(sent[0]) != "count:1|c" {
+		t.Fatalf("got '%s' expected 'count:1|c'", sent)
+	}
+
+	// Close sends what is left
+	rs.Clear()
+	c.Inc("count", 2, 0.1)
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	sent = rs.GetSent()
+	if len(sent) != 1 || string(sent[0]) != "count:2|c" {
+		t.Fatalf("got '%s' expected 'count:2|c'", sent)
+	}
+}
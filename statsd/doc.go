@@ -21,5 +21,10 @@ Example usage:
 		log.Printf("Error sending metric: %+v", err)
 	}
 
+When stats are disabled, use a NoopClient instead of a nil *Client, so that
+code can hold a non-nil Statter unconditionally:
+
+	client, err := statsd.NewNoopClient()
+
 */
 package statsd
@@ -0,0 +1,93 @@
+package statsd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Service check statuses, as defined by DogStatsD.
+const (
+	ServiceCheckOK       = 0
+	ServiceCheckWarning  = 1
+	ServiceCheckCritical = 2
+	ServiceCheckUnknown  = 3
+)
+
+// eventReplacer escapes newlines in event titles and texts, which would
+// otherwise end the event.
+var eventReplacer = strings.NewReplacer("\n", `\n`)
+
+// appendEventTags appends the client tags merged with tags, DogStatsD style.
+func (s *Client) appendEventTags(buf []byte, tags []Tag) []byte {
+	tagString := s.tagString
+	if s.tagFormat != TagFormatDatadog || len(tags) > 0 {
+		tagString = formatTags(mergeTags(s.tags, tags), TagFormatDatadog)
+	}
+	if tagString != "" {
+		buf = append(buf, "|#"...)
+		buf = append(buf, tagString...)
+	}
+	return buf
+}
+
+// Submits a DogStatsD service check, as "_sc|name|status|#tags".
+// name is the name of the check. It is not prefixed with the client prefix.
+// status is one of ServiceCheckOK, ServiceCheckWarning, ServiceCheckCritical
+// or ServiceCheckUnknown.
+// tags are added to those of the client.
+func (s *Client) ServiceCheck(name string, status int, tags ...Tag) error {
+	if s == nil {
+		return nil
+	}
+	if s.closer.isClosed() {
+		return ErrClosed
+	}
+	if name == "" || strings.ContainsAny(name, "|\n") {
+		return fmt.Errorf("Invalid service check name %q", name)
+	}
+	if status < ServiceCheckOK || status > ServiceCheckUnknown {
+		return fmt.Errorf("Invalid service check status %d", status)
+	}
+
+	buf := make([]byte, 0, 64)
+	buf = append(buf, "_sc|"...)
+	buf = append(buf, name...)
+	buf = append(buf, '|')
+	buf = strconv.AppendInt(buf, int64(status), 10)
+	buf = s.appendEventTags(buf, tags)
+	return s.sendMetrics(buf, 1)
+}
+
+// Submits a DogStatsD event, as "_e{title.length,text.length}:title|text|#tags",
+// where the lengths are in bytes.
+// title is the title of the event, and text its body. Newlines in either are
+// escaped. They are not prefixed with the client prefix.
+// tags are added to those of the client.
+func (s *Client) Event(title, text string, tags ...Tag) error {
+	if s == nil {
+		return nil
+	}
+	if s.closer.isClosed() {
+		return ErrClosed
+	}
+	if title == "" {
+		return errors.New("Empty event title")
+	}
+
+	title = eventReplacer.Replace(title)
+	text = eventReplacer.Replace(text)
+
+	buf := make([]byte, 0, len(title)+len(text)+32)
+	buf = append(buf, "_e{"...)
+	buf = strconv.AppendInt(buf, int64(len(title)), 10)
+	buf = append(buf, ',')
+	buf = strconv.AppendInt(buf, int64(len(text)), 10)
+	buf = append(buf, "}:"...)
+	buf = append(buf, title...)
+	buf = append(buf, '|')
+	buf = append(buf, text...)
+	buf = s.appendEventTags(buf, tags)
+	return s.sendMetrics(buf, 1)
+}
@@ -0,0 +1,78 @@
+package statsd
+
+import "testing"
+
+var serviceCheckTests = []struct {
+	Name     string
+	Status   int
+	Tags     []Tag
+	Expected string
+}{
+	{"db.up", ServiceCheckOK, nil, "_sc|db.up|0|#env:prod"},
+	{"db.up", ServiceCheckCritical, []Tag{{"db", "main"}}, "_sc|db.up|2|#env:prod,db:main"},
+}
+
+func TestClientServiceCheck(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithPrefix("test"), WithTags(Tag{"env", "prod"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for _, tt := range serviceCheckTests {
+		rs.Clear()
+		if err := c.ServiceCheck(tt.Name, tt.Status, tt.Tags...); err != nil {
+			t.Fatal(err)
+		}
+		sent := rs.GetSent()
+		if len(sent) != 1 || string(sent[0]) != tt.Expected {
+			t.Fatalf("got '%s' expected '%s'", sent, tt.Expected)
+		}
+	}
+
+	if err := c.ServiceCheck("db.up", 4); err == nil {
+		t.Fatal("expected an error for an invalid status")
+	}
+	if err := c.ServiceCheck("db|up", ServiceCheckOK); err == nil {
+		t.Fatal("expected an error for an invalid name")
+	}
+}
+
+var eventTests = []struct {
+	Title    string
+	Text     string
+	Tags     []Tag
+	Expected string
+}{
+	{"deploy", "v1.2.3 released", nil, "_e{6,15}:deploy|v1.2.3 released"},
+	{"deploy", "", []Tag{{"env", "prod"}}, "_e{6,0}:deploy||#env:prod"},
+	// newlines are escaped, and counted as escaped
+	{"deploy", "line1\nline2", nil, `_e{6,12}:deploy|line1\nline2`},
+	// lengths are in bytes, not runes
+	{"déploy", "ok", nil, "_e{7,2}:déploy|ok"},
+}
+
+func TestClientEvent(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithPrefix("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for _, tt := range eventTests {
+		rs.Clear()
+		if err := c.Event(tt.Title, tt.Text, tt.Tags...); err != nil {
+			t.Fatal(err)
+		}
+		sent := rs.GetSent()
+		if len(sent) != 1 || string(sent[0]) != tt.Expected {
+			t.Fatalf("got '%s' expected '%s'", sent, tt.Expected)
+		}
+	}
+
+	if err := c.Event("", "text"); err == nil {
+		t.Fatal("expected an error for an empty title")
+	}
+}
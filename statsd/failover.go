@@ -0,0 +1,136 @@
+package statsd
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// defaultFailbackInterval is how long a FailoverSender sends to a secondary
+// address before trying the primary again.
+const defaultFailbackInterval = 30 * time.Second
+
+// FailoverSender provides a send interface to a primary address, failing
+// over to secondary addresses in order when sending fails.
+//
+// As UDP writes rarely fail, failover mostly happens when an address cannot
+// be resolved, or the operating system reports an error such as no route to
+// the host. A server that is down is not detected, as nothing is sent back.
+type FailoverSender struct {
+	addrs     []string
+	newSender func(addr string) (Sender, error)
+	failback  time.Duration
+	// guards the fields below
+	mx sync.Mutex
+	// senders for each address, nil until created successfully
+	senders []Sender
+	// index of the address currently sent to
+	current int
+	// when the current address was failed over to
+	failedAt time.Time
+	closed   bool
+}
+
+// sender returns the sender for the address at i, creating it if needed.
+// Must be called with the mutex held.
+func (s *FailoverSender) sender(i int) (Sender, error) {
+	if s.senders[i] == nil {
+		sender, err := s.newSender(s.addrs[i])
+		if err != nil {
+			return nil, err
+		}
+		s.senders[i] = sender
+	}
+	return s.senders[i], nil
+}
+
+// Send sends the data to the current address. If that fails, each following
+// address is tried in turn, and the first to succeed becomes the current
+// address. After sending to a secondary address for the failback interval,
+// the primary is tried first again. If every address fails, the errors are
+// joined together.
+func (s *FailoverSender) Send(data []byte) (int, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.closed {
+		return 0, ErrClosed
+	}
+
+	if s.current != 0 && time.Since(s.failedAt) >= s.failback {
+		s.current = 0
+	}
+
+	var errs []error
+	for i := 0; i < len(s.addrs); i++ {
+		idx := (s.current + i) % len(s.addrs)
+		sender, err := s.sender(idx)
+		if err == nil {
+			var n int
+			n, err = sender.Send(data)
+			if err == nil {
+				if idx != s.current {
+					s.current = idx
+					s.failedAt = time.Now()
+				}
+				return n, nil
+			}
+		}
+		errs = append(errs, err)
+	}
+	return 0, errors.Join(errs...)
+}
+
+// Closes the sender for every address, joining any errors together.
+// Later calls return nil, and Send returns ErrClosed once closed.
+func (s *FailoverSender) Close() error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	var errs []error
+	for _, sender := range s.senders {
+		if sender == nil {
+			continue
+		}
+		if err := sender.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Returns a new FailoverSender, sending to the first of addrs, and failing
+// over to the others in order. Each is a string of the format
+// "hostname:port", sent to with a SimpleSender. An address that cannot be
+// resolved is retried when it is next failed over to, so an error is only
+// returned if none can be.
+func NewFailoverSender(addrs []string) (Sender, error) {
+	return newFailoverSender(addrs, NewSimpleSender)
+}
+
+func newFailoverSender(addrs []string, newSender func(addr string) (Sender, error)) (Sender, error) {
+	if len(addrs) == 0 {
+		return nil, errors.New("At least one address is required")
+	}
+
+	s := &FailoverSender{
+		addrs:     addrs,
+		newSender: newSender,
+		failback:  defaultFailbackInterval,
+		senders:   make([]Sender, len(addrs)),
+	}
+
+	var errs []error
+	for i := range addrs {
+		if _, err := s.sender(i); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == len(addrs) {
+		return nil, errors.Join(errs...)
+	}
+	return s, nil
+}
@@ -0,0 +1,145 @@
+package statsd
+
+import (
+	"errors"
+	"testing"
+)
+
+// failoverTargets creates senders for the failover tests, failing for
+// addresses in down.
+type failoverTargets struct {
+	senders map[string]*RecordingSender
+	down    map[string]bool
+}
+
+func newFailoverTargets(addrs ...string) *failoverTargets {
+	ft := &failoverTargets{
+		senders: make(map[string]*RecordingSender),
+		down:    make(map[string]bool),
+	}
+	for _, addr := range addrs {
+		ft.senders[addr] = NewRecordingSender()
+	}
+	return ft
+}
+
+func (ft *failoverTargets) newSender(addr string) (Sender, error) {
+	if ft.down[addr] {
+		return nil, errors.New("no such host")
+	}
+	return failoverTarget{ft, addr}, nil
+}
+
+type failoverTarget struct {
+	ft   *failoverTargets
+	addr string
+}
+
+func (t failoverTarget) Send(data []byte) (int, error) {
+	if t.ft.down[t.addr] {
+		return 0, errors.New("network unreachable")
+	}
+	return t.ft.senders[t.addr].Send(data)
+}
+
+func (t failoverTarget) Close() error { return nil }
+
+func TestFailoverSender(t *testing.T) {
+	ft := newFailoverTargets("primary", "secondary")
+	s, err := newFailoverSender([]string{"primary", "secondary"}, ft.newSender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	fs := s.(*FailoverSender)
+
+	send := func(expected string) {
+		t.Helper()
+		if _, err := s.Send([]byte("test.count:1|c")); err != nil {
+			t.Fatal(err)
+		}
+		for addr, rs := range ft.senders {
+			n := len(rs.GetSent())
+			if (addr == expected) != (n == 1) {
+				t.Fatalf("%s got %d sent, expected to send to %s", addr, n, expected)
+			}
+			rs.Clear()
+		}
+	}
+
+	send("primary")
+	ft.down["primary"] = true
+	send("secondary")
+	// the secondary is remembered until the failback interval passes
+	ft.down["primary"] = false
+	send("secondary")
+	fs.failback = 0
+	send("primary")
+
+	ft.down["primary"] = true
+	ft.down["secondary"] = true
+	if _, err := s.Send([]byte("test.count:1|c")); err == nil {
+		t.Fatal("expected an error when every address fails")
+	}
+}
+
+func TestFailoverSenderUnresolved(t *testing.T) {
+	ft := newFailoverTargets("primary", "secondary")
+	ft.down["primary"] = true
+	s, err := newFailoverSender([]string{"primary", "secondary"}, ft.newSender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.(*FailoverSender).failback = 0
+
+	s.Send([]byte("test.count:1|c"))
+	if n := len(ft.senders["secondary"].GetSent()); n != 1 {
+		t.Fatalf("got %d sent to the secondary expected 1", n)
+	}
+
+	// the primary is created once it resolves
+	ft.down["primary"] = false
+	s.Send([]byte("test.count:1|c"))
+	if n := len(ft.senders["primary"].GetSent()); n != 1 {
+		t.Fatalf("got %d sent to the primary expected 1", n)
+	}
+
+	_, err = newFailoverSender([]string{"primary", "secondary"}, func(string) (Sender, error) {
+		return nil, errors.New("no such host")
+	})
+	if err == nil {
+		t.Fatal("expected an error when no address resolves")
+	}
+}
+
+func TestNewFailoverSender(t *testing.T) {
+	l, err := newUDPListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	s, err := NewFailoverSender([]string{"127.0.0.1:bad", l.LocalAddr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	_, err = s.Send([]byte("test.count:1|c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 128)
+	n, _, err := l.ReadFrom(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data[:n]) != "test.count:1|c" {
+		t.Fatalf("got '%s' expected 'test.count:1|c'", data[:n])
+	}
+
+	if _, err := NewFailoverSender(nil); err == nil {
+		t.Fatal("expected an error for no addresses")
+	}
+}
@@ -0,0 +1,172 @@
+package statsd
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// gaugeDelta is the net change of a gauge, and the client to send it with.
+type gaugeDelta struct {
+	client *Client
+	stat   string
+	delta  int64
+	fdelta float64
+	float  bool
+}
+
+// gaugeDeltas accumulates gauge deltas for WithGaugeDeltaCoalescing, sending
+// the net change of each gauge every interval until stopped.
+type gaugeDeltas struct {
+	mx      sync.Mutex
+	pending map[string]*gaugeDelta
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func newGaugeDeltas() *gaugeDeltas {
+	return &gaugeDeltas{
+		pending: make(map[string]*gaugeDelta),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// start sends the pending deltas every interval, until stop is called.
+func (g *gaugeDeltas) start(interval time.Duration) {
+	go func() {
+		defer close(g.stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				g.flush()
+			case <-g.done:
+				return
+			}
+		}
+	}()
+}
+
+// stop stops sending pending deltas, and waits for a send in progress.
+func (g *gaugeDeltas) stop() {
+	close(g.done)
+	<-g.stopped
+}
+
+// add adds delta, or fdelta if float, to the pending delta for key.
+func (g *gaugeDeltas) add(key string, client *Client, stat string, delta int64, fdelta float64, float bool) {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+	d, ok := g.pending[key]
+	if !ok {
+		d = &gaugeDelta{client: client, stat: stat}
+		g.pending[key] = d
+	}
+	d.delta += delta
+	d.fdelta += fdelta
+	d.float = d.float || float
+}
+
+// flush sends every pending delta that is not 0, joining any errors
+// together, and empties the pending deltas.
+func (g *gaugeDeltas) flush() error {
+	g.mx.Lock()
+	pending := g.pending
+	g.pending = make(map[string]*gaugeDelta)
+	g.mx.Unlock()
+
+	var errs []error
+	for _, d := range pending {
+		if err := d.send(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// flushKey sends the pending delta for key, if any, and removes it.
+func (g *gaugeDeltas) flushKey(key string) error {
+	g.mx.Lock()
+	d, ok := g.pending[key]
+	delete(g.pending, key)
+	g.mx.Unlock()
+	if !ok {
+		return nil
+	}
+	return d.send()
+}
+
+// send sends the net change, unless it is 0.
+func (d *gaugeDelta) send() error {
+	var b [33]byte
+	v := b[:0]
+	if d.float {
+		f := float64(d.delta) + d.fdelta
+		if f == 0 {
+			return nil
+		}
+		if f > 0 {
+			v = append(v, '+')
+		}
+		v = appendFloat(v, f)
+	} else {
+		if d.delta == 0 {
+			return nil
+		}
+		if d.delta > 0 {
+			v = append(v, '+')
+		}
+		v = strconv.AppendInt(v, d.delta, 10)
+	}
+	return d.client.sendGaugeDelta(d.stat, v)
+}
+
+// addGaugeDelta adds a gauge delta for WithGaugeDeltaCoalescing to the net
+// change of the stat, if sampled in, to be sent after the interval.
+func (s *Client) addGaugeDelta(stat string, delta int64, fdelta float64, float bool, rate float32) error {
+	stat, rate, ok, err := s.check(stat, "|g", rate)
+	if !ok {
+		return err
+	}
+	if !s.sampled(stat, rate) {
+		atomic.AddUint64(&s.stats.sampledOut, 1)
+		return nil
+	}
+	s.gaugeDeltas.add(s.gaugeDeltaKey(stat), s, stat, delta, fdelta, float)
+	return nil
+}
+
+// gaugeDeltaKey returns the key of the pending delta for stat, which has
+// already been checked.
+func (s *Client) gaugeDeltaKey(stat string) string {
+	return s.getPrefix() + s.separator + stat + s.tagString
+}
+
+// flushGaugeDelta sends the pending delta for stat, if coalescing gauge
+// deltas, before the gauge is set, so that the server applies them in the
+// order called rather than adding the delta to the new value.
+func (s *Client) flushGaugeDelta(stat string) error {
+	if s == nil || s.gaugeDeltas == nil {
+		return nil
+	}
+	name, err := s.name(stat)
+	if err != nil {
+		return nil
+	}
+	return s.gaugeDeltas.flushKey(s.gaugeDeltaKey(name))
+}
+
+// sendGaugeDelta sends the formatted net change of a gauge for stat, which
+// has already been checked, without a rate.
+func (s *Client) sendGaugeDelta(stat string, value []byte) error {
+	bp := bufPool.Get().(*[]byte)
+	buf := s.appendMetric((*bp)[:0], stat, value, "|g", 1)
+	err := s.sendMetrics(buf, 1)
+	*bp = buf
+	bufPool.Put(bp)
+	return err
+}
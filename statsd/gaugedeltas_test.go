@@ -0,0 +1,145 @@
+package statsd
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClientGaugeDeltaCoalescing(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithPrefix("test"), WithGaugeDeltaCoalescing(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.GaugeDelta("depth", 5, 1.0)
+	c.GaugeDelta("depth", -2, 1.0)
+	c.GaugeDelta("depth", 1, 1.0)
+	c.GaugeDelta("down", -3, 1.0)
+	c.GaugeDelta("float", 1, 1.0)
+	c.GaugeDeltaFloat("float", 0.5, 1.0)
+	c.GaugeDelta("net", 2, 1.0)
+	c.GaugeDelta("net", -2, 1.0)
+	if sent := rs.GetSent(); len(sent) != 0 {
+		t.Fatalf("got '%s' expected nothing sent before the interval", sent)
+	}
+
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"test.depth:+4|g", "test.down:-3|g", "test.float:+1.5|g"}
+	var got []string
+	for _, s := range rs.GetSent() {
+		got = append(got, string(s))
+	}
+	sort.Strings(got)
+	if len(got) != len(expected) {
+		t.Fatalf("got %q expected %q", got, expected)
+	}
+	for i, e := range expected {
+		if got[i] != e {
+			t.Fatalf("got '%s' expected '%s'", got[i], e)
+		}
+	}
+
+	// reset after each flush
+	rs.Clear()
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if sent := rs.GetSent(); len(sent) != 0 {
+		t.Fatalf("got '%s' expected nothing sent after the flush", sent)
+	}
+}
+
+func TestClientGaugeDeltaCoalescingOrder(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithGaugeDeltaCoalescing(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.GaugeDelta("depth", 5, 1.0)
+	c.Gauge("depth", 10, 1.0)
+	c.GaugeDelta("depth", 2, 1.0)
+	c.GaugeDeltaFloat("float", 0.5, 1.0)
+	c.GaugeFloat("float", 1.5, 1.0)
+	c.GaugeDelta("uint", 1, 1.0)
+	c.GaugeUint64("uint", 3, 1.0)
+	c.GaugeDelta("other", 1, 1.0)
+	c.Gauge("unrelated", 1, 1.0)
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	// deltas pending when a gauge is set are sent before it, the rest on
+	// Flush
+	expected := []string{
+		"depth:+5|g", "depth:10|g",
+		"float:+0.5|g", "float:1.5|g",
+		"uint:+1|g", "uint:3|g",
+		"unrelated:1|g",
+	}
+	sent := rs.GetSent()
+	if len(sent) != len(expected)+2 {
+		t.Fatalf("got '%s' expected '%s' then the pending deltas", sent, expected)
+	}
+	for i, e := range expected {
+		if string(sent[i]) != e {
+			t.Fatalf("got '%s' expected '%s'", sent[i], e)
+		}
+	}
+	rest := []string{string(sent[len(expected)]), string(sent[len(expected)+1])}
+	sort.Strings(rest)
+	if rest[0] != "depth:+2|g" || rest[1] != "other:+1|g" {
+		t.Fatalf("got '%s' expected the deltas sent after the gauges", rest)
+	}
+}
+
+func TestClientGaugeDeltaCoalescingInterval(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithGaugeDeltaCoalescing(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				c.GaugeDelta("depth", 1, 1.0)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// the deltas may be split across intervals, but add up to the total
+	total := 0
+	deadline := time.Now().Add(time.Second)
+	for total < 100 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		total = 0
+		for _, s := range rs.GetSent() {
+			var n int
+			if _, err := fmt.Sscanf(string(s), "depth:+%d|g", &n); err != nil {
+				t.Fatalf("got '%s' expected a delta of depth", s)
+			}
+			total += n
+		}
+	}
+	if total != 100 {
+		t.Fatalf("got a total delta of %d expected 100", total)
+	}
+
+	if _, err := NewClientWithOptions(WithSender(rs), WithGaugeDeltaCoalescing(0)); err == nil {
+		t.Fatal("expected an error for an interval of 0")
+	}
+}
@@ -0,0 +1,32 @@
+package statsd
+
+import (
+	"os"
+	"strings"
+)
+
+// hostname returns the host name, and may be replaced in tests.
+var hostname = os.Hostname
+
+// WithHostnamePrefix returns a Statter that sends via c, with the host name
+// appended to the prefix of c, such as "service.web01", and an error if the
+// host name is unavailable. Dots in the host name are replaced with
+// underscores, so that it remains a single path element.
+func WithHostnamePrefix(c Statter) (Statter, error) {
+	host, err := hostname()
+	if err != nil {
+		return nil, err
+	}
+	return c.NewSubStatter(strings.ReplaceAll(host, ".", "_")), nil
+}
+
+// WithHostnameTag returns a Statter that sends via c, with a "host" tag of
+// the host name added to every metric, for tag based backends, and an error
+// if the host name is unavailable.
+func WithHostnameTag(c Statter) (Statter, error) {
+	host, err := hostname()
+	if err != nil {
+		return nil, err
+	}
+	return c.WithTags(Tag{"host", host}), nil
+}
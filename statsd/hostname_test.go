@@ -0,0 +1,59 @@
+package statsd
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestWithHostname(t *testing.T) {
+	hostname = func() (string, error) { return "web01.example.com", nil }
+	defer func() { hostname = os.Hostname }()
+
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithPrefix("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	p, err := WithHostnamePrefix(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Inc("count", 1, 1.0)
+
+	tagged, err := WithHostnameTag(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tagged.Inc("count", 1, 1.0)
+
+	expected := []string{
+		"test.web01_example_com.count:1|c",
+		"test.count:1|c|#host:web01.example.com",
+	}
+	sent := rs.GetSent()
+	if len(sent) != len(expected) {
+		t.Fatalf("got '%s' expected '%s'", sent, expected)
+	}
+	for i, e := range expected {
+		if string(sent[i]) != e {
+			t.Fatalf("got '%s' expected '%s'", sent[i], e)
+		}
+	}
+}
+
+func TestWithHostnameError(t *testing.T) {
+	hostErr := errors.New("no hostname")
+	hostname = func() (string, error) { return "", hostErr }
+	defer func() { hostname = os.Hostname }()
+
+	c := &NoopClient{}
+	if _, err := WithHostnamePrefix(c); err != hostErr {
+		t.Fatalf("got error '%v' expected '%v'", err, hostErr)
+	}
+	if _, err := WithHostnameTag(c); err != hostErr {
+		t.Fatalf("got error '%v' expected '%v'", err, hostErr)
+	}
+}
@@ -0,0 +1,94 @@
+package statsd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultHTTPFlushBytes is the largest body posted by an HTTP sender.
+	defaultHTTPFlushBytes = 64 * 1024
+	// defaultHTTPFlushInterval is the longest an HTTP sender holds data.
+	defaultHTTPFlushInterval = time.Second
+	// defaultHTTPTimeout is the timeout of the http.Client used when none
+	// is given.
+	defaultHTTPTimeout = 5 * time.Second
+)
+
+// httpSender posts the data of each Send to a URL.
+type httpSender struct {
+	url    string
+	client *http.Client
+	closed int32
+	// canceled by Close, to abandon posts in flight
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Send posts data as the body of a request, returning an error for a
+// response status other than 2xx.
+func (s *httpSender) Send(data []byte) (int, error) {
+	if atomic.LoadInt32(&s.closed) != 0 {
+		return 0, ErrClosed
+	}
+
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodPost, s.url, bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	// drained so that the connection may be reused
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return 0, fmt.Errorf("HTTP status %d posting metrics to %s", resp.StatusCode, s.url)
+	}
+	return len(data), nil
+}
+
+// Close marks the sender closed, and cancels any post in flight. Later calls
+// return nil.
+func (s *httpSender) Close() error {
+	atomic.StoreInt32(&s.closed, 1)
+	s.cancel()
+	return nil
+}
+
+// Returns a new Sender posting metrics to a collector at a URL over HTTP,
+// such as where UDP cannot be sent, as from serverless functions. Metrics are
+// buffered as with a BufferedSender, and posted as newline-delimited text in
+// the statsd format, once 64KiB are buffered, every second, on Flush, and on
+// Close. A response status other than 2xx is an error.
+//
+// A Send that fills the buffer waits for the post, so that functions which
+// may be frozen between invocations should call Flush, such as with
+// Client.Flush, before returning.
+//
+// rawURL must be an absolute http or https URL. If client is nil, an
+// http.Client with a timeout of five seconds is used.
+func NewHTTPSender(rawURL string, client *http.Client) (Sender, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("Unsupported URL scheme %q, must be http or https", u.Scheme)
+	}
+	if client == nil {
+		client = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sender := &httpSender{url: rawURL, client: client, ctx: ctx, cancel: cancel}
+	return newBufferedSender(sender, defaultHTTPFlushInterval, defaultHTTPFlushBytes, 0), nil
+}
@@ -0,0 +1,110 @@
+package statsd
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newHTTPCollector returns a server recording the body of each request, and
+// responding with status.
+func newHTTPCollector(status int) (*httptest.Server, func() []string) {
+	var mx sync.Mutex
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mx.Lock()
+		bodies = append(bodies, string(b))
+		mx.Unlock()
+		w.WriteHeader(status)
+	}))
+	return srv, func() []string {
+		mx.Lock()
+		defer mx.Unlock()
+		return append([]string(nil), bodies...)
+	}
+}
+
+func TestHTTPSender(t *testing.T) {
+	srv, bodies := newHTTPCollector(http.StatusNoContent)
+	defer srv.Close()
+
+	s, err := NewHTTPSender(srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newClient(s, "test")
+
+	c.Inc("count", 1, 1.0)
+	c.Gauge("gauge", 2, 1.0)
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	c.Inc("count", 3, 1.0)
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"test.count:1|c\ntest.gauge:2|g\n", "test.count:3|c\n"}
+	got := bodies()
+	if len(got) != len(expected) {
+		t.Fatalf("got %q expected %q", got, expected)
+	}
+	for i, e := range expected {
+		if got[i] != e {
+			t.Fatalf("got %q expected %q", got[i], e)
+		}
+	}
+}
+
+func TestHTTPSenderErrors(t *testing.T) {
+	srv, _ := newHTTPCollector(http.StatusInternalServerError)
+	defer srv.Close()
+
+	s, err := NewHTTPSender(srv.URL, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	s.Send([]byte("test.count:1|c"))
+	if err := s.(*BufferedSender).Flush(); err == nil {
+		t.Fatal("expected an error for a 500 response")
+	}
+
+	if _, err := NewHTTPSender("udp://127.0.0.1:8125", nil); err == nil {
+		t.Fatal("expected an error for a non http URL")
+	}
+}
+
+func TestHTTPSenderCloseWithTimeout(t *testing.T) {
+	unblock := make(chan bool)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// never responds while the test runs
+		<-unblock
+	}))
+	defer srv.Close()
+	defer close(unblock)
+
+	// a client without a timeout, so only Close abandons the post
+	s, err := NewHTTPSender(srv.URL, &http.Client{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Send([]byte("test.count:1|c")); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	err = s.(*BufferedSender).CloseWithTimeout(50 * time.Millisecond)
+	if !errors.Is(err, ErrCloseTimeout) {
+		t.Fatalf("got error '%v' expected '%v'", err, ErrCloseTimeout)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("CloseWithTimeout took %v expected about 50ms", d)
+	}
+}
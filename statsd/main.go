@@ -1,53 +1,501 @@
 package statsd
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"math/rand"
 	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
+	"unsafe"
 )
 
 type Statter interface {
 	Inc(stat string, value int64, rate float32) error
 	Dec(stat string, value int64, rate float32) error
+	IncMany(counts map[string]int64, rate float32) error
 	Gauge(stat string, value int64, rate float32) error
 	GaugeDelta(stat string, value int64, rate float32) error
+	GaugeFloat(stat string, value float64, rate float32) error
+	GaugeUint64(stat string, value uint64, rate float32) error
+	GaugeTime(stat string, t time.Time, rate float32) error
+	GaugeTimeMillis(stat string, t time.Time, rate float32) error
+	RateGauge(stat string, count int64, window time.Duration, rate float32) error
+	GaugeDeltaFloat(stat string, value float64, rate float32) error
 	Timing(stat string, delta int64, rate float32) error
 	TimingDuration(stat string, delta time.Duration, rate float32) error
+	SampledTiming(stat string, delta time.Duration, rate float32) error
+	TimingGauge(stat string, delta time.Duration, rate float32) error
+	TimingMulti(stat string, deltas []time.Duration, rate float32) error
+	Set(stat string, value string, rate float32) error
+	Histogram(stat string, value int64, rate float32) error
+	HistogramFloat(stat string, value float64, rate float32) error
+	Distribution(stat string, value float64, rate float32) error
+	Meter(stat string, value int64, rate float32) error
+	Custom(stat string, value string, suffix string, rate float32) error
+	NewTiming() Timing
+	Counter(stat string, rate float32) Counter
+	BoundGauge(stat string, rate float32) Gauge
+	Timer(stat string, rate float32) Timer
+	Time(stat string, rate float32, f func()) error
+	Recover(stat string) func()
+	NewBatch() *Batch
 	Raw(stat string, value string, rate float32) error
+	RawBytes(stat []byte, value []byte, rate float32) error
+	ServiceCheck(name string, status int, tags ...Tag) error
+	Event(title, text string, tags ...Tag) error
 	SetPrefix(prefix string)
+	WithTags(tags ...Tag) Statter
+	NewSubStatter(prefix string) Statter
+	WithContext(ctx context.Context) Statter
+	Flush() error
+	Enabled() bool
+	Stats() ClientStats
+	PublishExpvar(name string)
 	Close() error
 }
 
+// UseDefaultRate may be passed as the rate of any method to use the default
+// rate configured for the metric type with WithTypeDefaultRate, falling back
+// to the one set with WithDefaultRate, and to sampling everything. An
+// explicit rate always overrides the defaults. Other negative rates are
+// invalid.
+const UseDefaultRate float32 = -1
+
+// ErrClosed is returned when sending with a client, or a sender, that has
+// been closed.
+var ErrClosed = errors.New("statsd: client closed")
+
+// ErrCloseTimeout is returned by CloseWithTimeout when pending data could not
+// be sent before the timeout.
+var ErrCloseTimeout = errors.New("statsd: timed out sending pending data on close")
+
+// ErrInvalidValue is returned by the methods taking a floating point value
+// when it is NaN or infinite, which statsd servers cannot parse. Nothing is
+// sent.
+var ErrInvalidValue = errors.New("statsd: invalid value")
+
+// defaultCloseTimeout bounds how long Close waits for pending data to be sent,
+// for senders that send from a background goroutine.
+const defaultCloseTimeout = time.Second
+
+// closedErr maps the error from using a closed connection to ErrClosed.
+func closedErr(err error) error {
+	if errors.Is(err, net.ErrClosed) {
+		return ErrClosed
+	}
+	return err
+}
+
+// ErrResolveAddr is matched, with errors.Is, by the error returned when a
+// sender constructor cannot resolve its address, such as for a failed DNS
+// lookup, which may be worth retrying. The error names the address and
+// wraps the cause, so that a *net.DNSError can be found with errors.As.
+var ErrResolveAddr = errors.New("statsd: cannot resolve address")
+
+// ErrListen is matched, with errors.Is, by the error returned when a sender
+// constructor cannot create its socket. The error wraps the cause.
+var ErrListen = errors.New("statsd: cannot create socket")
+
+// resolveErr wraps err, from resolving addr, in ErrResolveAddr.
+func resolveErr(addr string, err error) error {
+	return fmt.Errorf("%w %q: %w", ErrResolveAddr, addr, err)
+}
+
+// listenErr wraps err, from creating a socket, in ErrListen.
+func listenErr(err error) error {
+	return fmt.Errorf("%w: %w", ErrListen, err)
+}
+
+// ErrPacketTooLarge is matched, with errors.Is, by the PacketTooLargeError
+// returned when a datagram exceeds the limit of the operating system, and by
+// the error returned for metrics larger than the size set with
+// WithMaxPacketSize.
+var ErrPacketTooLarge = errors.New("statsd: packet too large")
+
+// PacketTooLargeError is returned by the UDP senders when the operating
+// system rejects a datagram as too long (EMSGSIZE), such as to fall back to
+// smaller batches. It matches ErrPacketTooLarge with errors.Is, and unwraps
+// to the underlying error.
+type PacketTooLargeError struct {
+	// Size is the length of the payload in bytes.
+	Size int
+	// Limit is the largest UDP payload possible for the address family. The
+	// operating system limit may be lower.
+	Limit int
+	// Err is the error returned by the write.
+	Err error
+}
+
+func (e *PacketTooLargeError) Error() string {
+	return fmt.Sprintf("statsd: packet of %d bytes too large, limit is at most %d bytes: %v", e.Size, e.Limit, e.Err)
+}
+
+func (e *PacketTooLargeError) Is(target error) bool {
+	return target == ErrPacketTooLarge
+}
+
+func (e *PacketTooLargeError) Unwrap() error {
+	return e.Err
+}
+
+// ErrSendBufferFull is matched, with errors.Is, by the error returned by
+// SimpleSender when the socket send buffer is still full after a retry.
+var ErrSendBufferFull = errors.New("statsd: send buffer full")
+
+// sendBufferFullBackoff is how long SimpleSender waits before retrying a
+// write rejected as the send buffer is full.
+var sendBufferFullBackoff = time.Millisecond
+
+// retryBufferFull calls write, and once more after sendBufferFullBackoff if
+// it fails with ENOBUFS or EAGAIN, as the socket send buffer is full. If the
+// retry fails the same way the error wraps ErrSendBufferFull.
+func retryBufferFull(write func() (int, error)) (int, error) {
+	n, err := write()
+	if !isBufferFull(err) {
+		return n, err
+	}
+	time.Sleep(sendBufferFullBackoff)
+	n, err = write()
+	if isBufferFull(err) {
+		return n, fmt.Errorf("%w: %w", ErrSendBufferFull, err)
+	}
+	return n, err
+}
+
+func isBufferFull(err error) bool {
+	return errors.Is(err, syscall.ENOBUFS) || errors.Is(err, syscall.EAGAIN)
+}
+
+// udpErr maps the error from writing a datagram of size bytes to ra, to
+// ErrClosed or a PacketTooLargeError where it applies.
+func udpErr(err error, size int, ra *net.UDPAddr) error {
+	if errors.Is(err, syscall.EMSGSIZE) {
+		limit := 65507 // 65535 - 8 byte UDP header - 20 byte IPv4 header
+		if ra != nil && ra.IP.To4() == nil {
+			limit = 65527 // 65535 - 8 byte UDP header
+		}
+		return &PacketTooLargeError{Size: size, Limit: limit, Err: err}
+	}
+	return closedErr(err)
+}
+
+// closeConn closes c, treating a connection that is already closed as
+// success, so that closing is idempotent.
+func closeConn(c io.Closer) error {
+	err := c.Close()
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// Sender sends formatted metrics. Implementations must not retain data after
+// Send returns, as the client reuses it.
 type Sender interface {
 	Send(data []byte) (int, error)
 	Close() error
 }
 
+// Addressable is implemented by Senders that send to a single remote
+// address, such as SimpleSender, for logging or checking where metrics are
+// sent after resolution.
+type Addressable interface {
+	RemoteAddr() net.Addr
+}
+
+// Reconnecter is implemented by Senders that can replace their connection,
+// such as TCPSender and UnixgramSender, to recover from a broken connection
+// without creating a new client. Datagram senders that are not connected,
+// such as SimpleSender, implement it as a no-op.
+type Reconnecter interface {
+	Reconnect() error
+}
+
+// Pinger is implemented by Senders that can check that the server is
+// reachable without sending a metric, such as TCPSender and UnixgramSender.
+type Pinger interface {
+	Ping() error
+}
+
+// errNoPinger is returned by the Ping of senders wrapping another sender that
+// does not implement Pinger, such as RateLimitedSender, for Client.Ping to
+// send a metric instead.
+var errNoPinger = errors.New("statsd: sender does not implement Pinger")
+
+// contextSender is implemented by Senders whose writes may block, and which
+// can abort a write when a context is done, such as TCPSender.
+type contextSender interface {
+	SendContext(ctx context.Context, data []byte) (int, error)
+}
+
+// flusher is implemented by Senders that hold pending data, such as
+// BufferedSender.
+type flusher interface {
+	Flush() error
+}
+
 type Client struct {
-	// prefix for statsd name
-	prefix string
+	// prefix for statsd name, guarded by prefixMx
+	prefix   string
+	prefixMx sync.RWMutex
+	// separator between prefix and stat name
+	separator string
+	// appended to stat names after the separator, if set
+	suffix string
+	// handling of reserved characters in stat names
+	nameMode NameMode
+	// applied to stat names before prefixing, if set
+	sanitizer func(string) string
+	// names changed by the sanitizer or nameMode, shared with derived clients
+	names *nameCache
 	// packet sender
 	sender Sender
+	// DogStatsD tags appended to every metric
+	tags []Tag
+	// tags formatted for the wire
+	tagString string
+	tagFormat TagFormat
+	// random number generator used for sampling
+	rng *lockedRand
+	// decides which metrics are sampled in, in place of rng, if set
+	sampler Sampler
+	// sample rate used when a rate of 0 or UseDefaultRate is given, if
+	// non-zero
+	defaultRate float32
+	// sample rates used by metric type when a rate of 0 or UseDefaultRate
+	// is given, read only once the client is created
+	typeRates map[string]float32
+	// sample rates set at runtime by stat prefix, shared with derived clients
+	overrides *rateOverrides
+	// true if the sender is shared with, and owned by, a parent client
+	derived bool
+	// context that sends are bound to, if set with WithContext
+	ctx context.Context
+	// counts of metrics sent and lost
+	stats *clientStats
+	// whether the sender has been closed, shared with derived clients
+	closer *closer
+	// called with any error returned by the sender
+	onError func(err error)
+	// called with every payload before it is sent, if set
+	logger func(payload []byte)
+	// largest payload handed to the sender, if not 0
+	maxPacketSize int
+	// terminate each payload with a newline
+	newline bool
+	// omit the "|@rate" of sampled metrics
+	noRateSuffix bool
+	// counter incremented by Shutdown
+	shutdownStat string
+	// records payloads instead of sending them, if set with WithDryRun
+	recorder *RecordingSender
+	// source of the current time for timings
+	clock Clock
+	// send TimingDuration as whole milliseconds
+	roundTimings bool
+	// decimal places of TimingDuration milliseconds
+	timingPrecision int
+	// how timings sent as zero are handled
+	zeroTimings ZeroTimingPolicy
+	// when SampledTiming last sent each stat, shared with derived clients
+	emissions *emissions
+	// longest SampledTiming goes without sending a stat
+	emitInterval time.Duration
+	// sampled out counters, if set with WithSampleRateGuarantee, shared with
+	// derived clients
+	counts *counterTotals
+	// pending gauge deltas, if set with WithGaugeDeltaCoalescing, shared
+	// with derived clients
+	gaugeDeltas *gaugeDeltas
+}
+
+// closer closes a sender once, and records that it has been closed.
+type closer struct {
+	once   sync.Once
+	closed int32
+}
+
+func (c *closer) isClosed() bool {
+	return atomic.LoadInt32(&c.closed) == 1
+}
+
+// lockedRand is a *rand.Rand that is safe for concurrent use.
+type lockedRand struct {
+	mx sync.Mutex
+	r  *rand.Rand
+}
+
+func newLockedRand(src rand.Source) *lockedRand {
+	return &lockedRand{r: rand.New(src)}
+}
+
+func (l *lockedRand) Float32() float32 {
+	l.mx.Lock()
+	f := l.r.Float32()
+	l.mx.Unlock()
+	return f
+}
+
+// newClient returns a *Client sending via sender, with a sampling rng seeded
+// from the current time.
+func newClient(sender Sender, prefix string) *Client {
+	return &Client{
+		prefix:          prefix,
+		separator:       ".",
+		sender:          sender,
+		rng:             newLockedRand(rand.NewSource(time.Now().UnixNano())),
+		shutdownStat:    "shutdown",
+		stats:           &clientStats{},
+		closer:          &closer{},
+		clock:           realClock{},
+		timingPrecision: 2,
+		overrides:       &rateOverrides{},
+		names:           newNameCache(),
+		emissions:       newEmissions(),
+		emitInterval:    defaultEmitInterval,
+	}
 }
 
-// Close closes the connection and cleans up.
+// Close closes the connection and cleans up, after sending any counter
+// totals pending with WithSampleRateGuarantee.
+// Calling Close more than once is safe, and later calls return nil. After
+// Close, sending returns ErrClosed, including from derived clients.
+// Closing a client derived with WithTags or NewSubStatter does nothing, as
+// the sender remains owned by the parent client.
 func (s *Client) Close() error {
+	if s == nil || s.derived {
+		return nil
+	}
+	var err error
+	s.closer.once.Do(func() {
+		if s.counts != nil {
+			s.counts.stop()
+			err = s.counts.flush()
+		}
+		if s.gaugeDeltas != nil {
+			s.gaugeDeltas.stop()
+			err = errors.Join(err, s.gaugeDeltas.flush())
+		}
+		atomic.StoreInt32(&s.closer.closed, 1)
+		err = errors.Join(err, s.sender.Close())
+	})
+	return err
+}
+
+// Shutdown records that the process is stopping, by incrementing the counter
+// set with WithShutdownStat, "shutdown" by default, then flushes and closes
+// the client, returning any errors joined together. Once the client is
+// closed it returns ErrClosed. As with Close, a client derived with WithTags
+// or NewSubStatter is not closed.
+func (s *Client) Shutdown() error {
 	if s == nil {
 		return nil
 	}
-	err := s.sender.Close()
+	err := s.Inc(s.shutdownStat, 1, 1.0)
+	if errors.Is(err, ErrClosed) {
+		return err
+	}
+	return errors.Join(err, s.Flush(), s.Close())
+}
+
+// Flush sends any data pending in the sender, if it buffers data, after the
+// counter totals pending with WithSampleRateGuarantee, if set.
+// For unbuffered senders this does nothing.
+func (s *Client) Flush() error {
+	if s == nil {
+		return nil
+	}
+	if s.closer.isClosed() {
+		return ErrClosed
+	}
+	var err error
+	if s.counts != nil {
+		err = s.counts.flush()
+	}
+	if s.gaugeDeltas != nil {
+		err = errors.Join(err, s.gaugeDeltas.flush())
+	}
+	if f, ok := s.sender.(flusher); ok {
+		err = errors.Join(err, f.Flush())
+	}
 	return err
 }
 
+// Sent returns the payloads that would have been sent by a client created
+// with WithDryRun, in order, and nil for other clients.
+func (s *Client) Sent() [][]byte {
+	if s == nil || s.recorder == nil {
+		return nil
+	}
+	return s.recorder.GetSent()
+}
+
+// Ping checks that the server is reachable, such as before relying on it at
+// startup. If the sender implements Pinger, such as TCPSender, its Ping is
+// used. Otherwise a count of 0 is sent to the "health.ping" stat, and the
+// sender flushed, returning any error. As UDP does not confirm delivery, this
+// only detects errors such as unreachable or invalid addresses, and closed
+// sockets, not whether a server is listening.
+func (s *Client) Ping() error {
+	if s == nil {
+		return nil
+	}
+	if s.closer.isClosed() {
+		return ErrClosed
+	}
+	if p, ok := s.sender.(Pinger); ok {
+		if err := p.Ping(); err != errNoPinger {
+			return err
+		}
+	}
+	if err := s.Inc("health.ping", 0, 1.0); err != nil {
+		return err
+	}
+	return s.Flush()
+}
+
+// Enabled reports whether metrics are sent, so that callers can skip work
+// done only to produce a metric value. It is true until the client is
+// closed.
+func (s *Client) Enabled() bool {
+	return s != nil && !s.closer.isClosed()
+}
+
+// Reconnect replaces the connection of the sender, if it implements
+// Reconnecter, such as to recover from a broken TCP connection. For other
+// senders this does nothing.
+func (s *Client) Reconnect() error {
+	if s == nil {
+		return nil
+	}
+	if s.closer.isClosed() {
+		return ErrClosed
+	}
+	if r, ok := s.sender.(Reconnecter); ok {
+		return r.Reconnect()
+	}
+	return nil
+}
+
 // Increments a statsd count type.
 // stat is a string name for the metric.
 // value is the integer value
-// rate is the sample rate (0.0 to 1.0)
+// rate is the sample rate (0.0 to 1.0). See WithSampleRateGuarantee for
+// sending exact counts regardless.
 func (s *Client) Inc(stat string, value int64, rate float32) error {
-	dap := fmt.Sprintf("%d|c", value)
-	return s.Raw(stat, dap, rate)
+	if s != nil && s.counts != nil {
+		return s.submitCount(stat, value, rate)
+	}
+	var b [20]byte
+	return s.submit(stat, strconv.AppendInt(b[:0], value, 10), "|c", rate)
 }
 
 // Decrements a statsd count type.
@@ -58,22 +506,221 @@ func (s *Client) Dec(stat string, value int64, rate float32) error {
 	return s.Inc(stat, -value, rate)
 }
 
+// Increments many statsd count types, sending them in as few packets as
+// possible, separated by newlines. Packets are split to stay within the size
+// set with WithMaxPacketSize, or 1432 bytes. Each counter is sampled on its
+// own. An invalid stat name does not prevent sending the others; any errors
+// are joined together.
+// counts maps the string names of the metrics to their integer values. They
+// are sent in no particular order.
+// rate is the sample rate (0.0 to 1.0).
+func (s *Client) IncMany(counts map[string]int64, rate float32) error {
+	if s == nil {
+		return nil
+	}
+	var errs []error
+	if s.counts != nil {
+		for stat, value := range counts {
+			if err := s.submitCount(stat, value, rate); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	maxSize := defaultMaxPacketSize
+	if s.maxPacketSize > 0 {
+		maxSize = s.maxPacketSize
+	}
+	if s.newline {
+		maxSize--
+	}
+
+	var v [20]byte
+	var n uint64
+	bp := bufPool.Get().(*[]byte)
+	buf := (*bp)[:0]
+	for stat, value := range counts {
+		stat, r, ok, err := s.prepare(stat, "|c", rate)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if !ok {
+			continue
+		}
+
+		start := len(buf)
+		if n > 0 {
+			buf = append(buf, '\n')
+		}
+		buf = s.appendMetric(buf, stat, strconv.AppendInt(v[:0], value, 10), "|c", r)
+		if n > 0 && len(buf) > maxSize {
+			// send the packet so far, and start the next with this metric
+			if err := s.sendMetrics(buf[:start], n); err != nil {
+				errs = append(errs, err)
+			}
+			buf = buf[:copy(buf, buf[start+1:])]
+			n = 0
+		}
+		n++
+	}
+	if n > 0 {
+		if err := s.sendMetrics(buf, n); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	*bp = buf
+	bufPool.Put(bp)
+	return errors.Join(errs...)
+}
+
 // Submits/Updates a statsd gauge type.
 // stat is a string name for the metric.
-// value is the integer value.
+// value is the integer value. As statsd treats a signed value as a delta, a
+// negative value is sent as a reset to 0 followed by the value, in a single
+// packet, so that the gauge is set to the value.
 // rate is the sample rate (0.0 to 1.0).
 func (s *Client) Gauge(stat string, value int64, rate float32) error {
-	dap := fmt.Sprintf("%d|g", value)
-	return s.Raw(stat, dap, rate)
+	if err := s.flushGaugeDelta(stat); err != nil {
+		return err
+	}
+	var b [20]byte
+	v := strconv.AppendInt(b[:0], value, 10)
+	if value < 0 {
+		return s.submitNegativeGauge(stat, v, rate)
+	}
+	return s.submit(stat, v, "|g", rate)
 }
 
 // Submits a delta to a statsd gauge.
 // stat is the string name for the metric.
-// value is the (positive or negative) change.
+// value is the (positive or negative) change. A zero change sends nothing,
+// as some servers treat "+0" as setting the gauge to 0.
 // rate is the sample rate (0.0 to 1.0).
 func (s *Client) GaugeDelta(stat string, value int64, rate float32) error {
-	dap := fmt.Sprintf("%+d|g", value)
-	return s.Raw(stat, dap, rate)
+	if value == 0 {
+		return nil
+	}
+	if s != nil && s.gaugeDeltas != nil {
+		return s.addGaugeDelta(stat, value, 0, false, rate)
+	}
+	var b [21]byte
+	v := b[:0]
+	if value >= 0 {
+		v = append(v, '+')
+	}
+	return s.submit(stat, strconv.AppendInt(v, value, 10), "|g", rate)
+}
+
+// Submits/Updates a statsd gauge type with a floating point value.
+// stat is a string name for the metric.
+// value is the float value. As with Gauge, a negative value is sent as a
+// reset to 0 followed by the value.
+// rate is the sample rate (0.0 to 1.0).
+func (s *Client) GaugeFloat(stat string, value float64, rate float32) error {
+	if err := checkFloat(value); err != nil {
+		return err
+	}
+	if err := s.flushGaugeDelta(stat); err != nil {
+		return err
+	}
+	var b [32]byte
+	v := appendFloat(b[:0], value)
+	if value < 0 {
+		return s.submitNegativeGauge(stat, v, rate)
+	}
+	return s.submit(stat, v, "|g", rate)
+}
+
+// Submits/Updates a statsd gauge type with an unsigned value, for values
+// that may exceed the range of int64, such as total bytes.
+// stat is a string name for the metric.
+// value is the unsigned integer value.
+// rate is the sample rate (0.0 to 1.0).
+func (s *Client) GaugeUint64(stat string, value uint64, rate float32) error {
+	if err := s.flushGaugeDelta(stat); err != nil {
+		return err
+	}
+	var b [20]byte
+	return s.submit(stat, strconv.AppendUint(b[:0], value, 10), "|g", rate)
+}
+
+// Submits/Updates a statsd gauge type with a time, as seconds since the Unix
+// epoch, such as for the time of the last successful run.
+// stat is a string name for the metric.
+// t is the time, sent as t.Unix().
+// rate is the sample rate (0.0 to 1.0).
+func (s *Client) GaugeTime(stat string, t time.Time, rate float32) error {
+	return s.Gauge(stat, t.Unix(), rate)
+}
+
+// Submits/Updates a statsd gauge type with a time, as milliseconds since the
+// Unix epoch.
+// stat is a string name for the metric.
+// t is the time, sent as t.UnixMilli().
+// rate is the sample rate (0.0 to 1.0).
+func (s *Client) GaugeTimeMillis(stat string, t time.Time, rate float32) error {
+	return s.Gauge(stat, t.UnixMilli(), rate)
+}
+
+// Submits/Updates a statsd gauge type with the rate per second of count
+// events over window, such as for throughput.
+// stat is a string name for the metric.
+// count is the number of events, and window the time they happened over,
+// sent as count / window.Seconds(). A window that is not positive returns
+// ErrInvalidValue.
+// rate is the sample rate (0.0 to 1.0).
+func (s *Client) RateGauge(stat string, count int64, window time.Duration, rate float32) error {
+	v, err := perSecond(count, window)
+	if err != nil {
+		return err
+	}
+	return s.GaugeFloat(stat, v, rate)
+}
+
+// perSecond returns count / window.Seconds(), or ErrInvalidValue if window
+// is not positive.
+func perSecond(count int64, window time.Duration) (float64, error) {
+	if window <= 0 {
+		return 0, ErrInvalidValue
+	}
+	return float64(count) / window.Seconds(), nil
+}
+
+// Submits a floating point delta to a statsd gauge.
+// stat is the string name for the metric.
+// value is the (positive or negative) change. A zero change sends nothing,
+// as some servers treat "+0" as setting the gauge to 0.
+// rate is the sample rate (0.0 to 1.0).
+func (s *Client) GaugeDeltaFloat(stat string, value float64, rate float32) error {
+	if err := checkFloat(value); err != nil {
+		return err
+	}
+	if value == 0 {
+		return nil
+	}
+	if s != nil && s.gaugeDeltas != nil {
+		return s.addGaugeDelta(stat, 0, value, true, rate)
+	}
+	var b [33]byte
+	v := b[:0]
+	if value >= 0 {
+		v = append(v, '+')
+	}
+	return s.submit(stat, appendFloat(v, value), "|g", rate)
+}
+
+// appendFloat appends f without an exponent or trailing zeros.
+func appendFloat(b []byte, f float64) []byte {
+	return strconv.AppendFloat(b, f, 'f', -1, 64)
+}
+
+// checkFloat returns ErrInvalidValue if f is NaN or infinite.
+func checkFloat(f float64) error {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return ErrInvalidValue
+	}
+	return nil
 }
 
 // Submits a statsd timing type.
@@ -81,8 +728,12 @@ func (s *Client) GaugeDelta(stat string, value int64, rate float32) error {
 // delta is the time duration value in milliseconds
 // rate is the sample rate (0.0 to 1.0).
 func (s *Client) Timing(stat string, delta int64, rate float32) error {
-	dap := fmt.Sprintf("%d|ms", delta)
-	return s.Raw(stat, dap, rate)
+	var b [20]byte
+	value, ok := s.zeroTiming(strconv.AppendInt(b[:0], delta, 10))
+	if !ok {
+		return nil
+	}
+	return s.submit(stat, value, "|ms", rate)
 }
 
 // Submits a statsd timing type.
@@ -90,49 +741,689 @@ func (s *Client) Timing(stat string, delta int64, rate float32) error {
 // delta is the timing value as time.Duration
 // rate is the sample rate (0.0 to 1.0).
 func (s *Client) TimingDuration(stat string, delta time.Duration, rate float32) error {
+	var b [32]byte
+	value, ok := s.zeroTiming(s.appendDuration(b[:0], delta))
+	if !ok {
+		return nil
+	}
+	return s.submit(stat, value, "|ms", rate)
+}
+
+// Submits many statsd timings for one stat in a single line, in the
+// multi-value format accepted by Telegraf, as "stat:1|ms:2|ms:3|ms". The line
+// is sampled as a whole, and every value carries the sample rate. Nothing is
+// sent for no deltas.
+// stat is a string name for the metric.
+// deltas are the timing values as time.Duration, formatted as for
+// TimingDuration.
+// rate is the sample rate (0.0 to 1.0).
+func (s *Client) TimingMulti(stat string, deltas []time.Duration, rate float32) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+	stat, rate, ok, err := s.prepare(stat, "|ms", rate)
+	if !ok {
+		return err
+	}
+
+	vp := bufPool.Get().(*[]byte)
+	value := (*vp)[:0]
+	var n uint64
+	for _, d := range deltas {
+		var b [32]byte
+		v, ok := s.zeroTiming(s.appendDuration(b[:0], d))
+		if !ok {
+			continue
+		}
+		if n > 0 {
+			value = append(value, "|ms"...)
+			value = s.appendRate(value, rate)
+			value = append(value, ':')
+		}
+		value = append(value, v...)
+		n++
+	}
+	if n == 0 {
+		*vp = value
+		bufPool.Put(vp)
+		return nil
+	}
+
+	bp := bufPool.Get().(*[]byte)
+	buf := s.appendMetric((*bp)[:0], stat, value, "|ms", rate)
+	err = s.sendMetrics(buf, n)
+	*bp, *vp = buf, value
+	bufPool.Put(bp)
+	bufPool.Put(vp)
+	return err
+}
+
+// Submits/Updates a statsd gauge type with a duration in milliseconds, for
+// the last observed latency rather than a distribution of timings.
+// stat is a string name for the metric.
+// delta is the timing value as time.Duration, formatted as for
+// TimingDuration.
+// rate is the sample rate (0.0 to 1.0).
+func (s *Client) TimingGauge(stat string, delta time.Duration, rate float32) error {
+	if err := s.flushGaugeDelta(stat); err != nil {
+		return err
+	}
+	var b [32]byte
+	v := s.appendDuration(b[:0], delta)
+	if delta < 0 {
+		return s.submitNegativeGauge(stat, v, rate)
+	}
+	return s.submit(stat, v, "|g", rate)
+}
+
+// appendDuration appends d formatted as milliseconds, with the client's
+// timing precision, or rounded to whole milliseconds if set with
+// WithRoundedTimings.
+func (s *Client) appendDuration(b []byte, d time.Duration) []byte {
+	ms := float64(d) / float64(time.Millisecond)
+	if s == nil {
+		return strconv.AppendFloat(b, ms, 'f', 2, 64)
+	}
+	if s.roundTimings {
+		return strconv.AppendInt(b, int64(math.Round(ms)), 10)
+	}
+	return strconv.AppendFloat(b, ms, 'f', s.timingPrecision, 64)
+}
+
+// Submits a stats set type.
+// stat is a string name for the metric.
+// value is the string value, and is not assumed to be numeric.
+// rate is the sample rate (0.0 to 1.0).
+func (s *Client) Set(stat string, value string, rate float32) error {
+	return s.submit(stat, []byte(value), "|s", rate)
+}
+
+// Submits a statsd histogram type.
+// stat is a string name for the metric.
+// value is the integer value.
+// rate is the sample rate (0.0 to 1.0).
+func (s *Client) Histogram(stat string, value int64, rate float32) error {
+	var b [20]byte
+	return s.submit(stat, strconv.AppendInt(b[:0], value, 10), "|h", rate)
+}
 
-	ms := float64(delta) / float64(time.Millisecond)
+// Submits a statsd histogram type with a floating point value.
+// stat is a string name for the metric.
+// value is the float value.
+// rate is the sample rate (0.0 to 1.0).
+func (s *Client) HistogramFloat(stat string, value float64, rate float32) error {
+	if err := checkFloat(value); err != nil {
+		return err
+	}
+	var b [32]byte
+	return s.submit(stat, appendFloat(b[:0], value), "|h", rate)
+}
 
-	dap := fmt.Sprintf("%.02f|ms", ms)
-	return s.Raw(stat, dap, rate)
+// Submits a DogStatsD distribution type, which is aggregated globally
+// across hosts. Only Datadog agents support this type.
+// stat is a string name for the metric.
+// value is the float value.
+// rate is the sample rate (0.0 to 1.0).
+func (s *Client) Distribution(stat string, value float64, rate float32) error {
+	if err := checkFloat(value); err != nil {
+		return err
+	}
+	var b [32]byte
+	return s.submit(stat, appendFloat(b[:0], value), "|d", rate)
+}
+
+// Submits a meter type, measuring the rate of events. Not all statsd servers
+// support this type.
+// stat is a string name for the metric.
+// value is the integer value
+// rate is the sample rate (0.0 to 1.0)
+func (s *Client) Meter(stat string, value int64, rate float32) error {
+	var b [20]byte
+	return s.submit(stat, strconv.AppendInt(b[:0], value, 10), "|m", rate)
+}
+
+// Submits a metric of a type without a method of its own, such as one
+// supported only by a particular server, sent as "stat:value|suffix".
+// stat is a string name for the metric.
+// value is the preformatted value string.
+// suffix is the metric type, such as "kv". It may not be empty or contain
+// any of ":|@#" or a newline.
+// rate is the sample rate (0.0 to 1.0).
+func (s *Client) Custom(stat string, value string, suffix string, rate float32) error {
+	if err := checkSuffix(suffix); err != nil {
+		return err
+	}
+	return s.submit(stat, []byte(value), "|"+suffix, rate)
+}
+
+// checkSuffix returns an error if suffix is not a valid metric type for
+// Custom.
+func checkSuffix(suffix string) error {
+	if suffix == "" || strings.ContainsAny(suffix, reservedChars+"#\n") {
+		return fmt.Errorf("Invalid metric type %q", suffix)
+	}
+	return nil
+}
+
+// Returns a Timing started now, which submits the elapsed time when sent.
+// Time is measured with the client's Clock.
+func (s *Client) NewTiming() Timing {
+	if s == nil || s.clock == nil {
+		return newTiming(s, realClock{})
+	}
+	return newTiming(s, s.clock)
+}
+
+// Calls f, and submits its duration as a statsd timing type.
+// stat is a string name for the metric.
+// rate is the sample rate (0.0 to 1.0).
+func (s *Client) Time(stat string, rate float32, f func()) error {
+	t := s.NewTiming()
+	f()
+	return t.Send(stat, rate)
+}
+
+// Returns a function that, if the goroutine is panicking, increments the
+// stat counter and then panics again with the same value, for use as
+// defer s.Recover("module.panic")().
+// stat is a string name for the metric.
+func (s *Client) Recover(stat string) func() {
+	return func() {
+		if r := recover(); r != nil {
+			s.Inc(stat, 1, 1.0)
+			panic(r)
+		}
+	}
 }
 
 // Raw formats the statsd event data, handles sampling, prepares it,
 // and sends it to the server.
-// stat is the string name for the metric.
+// stat is the string name for the metric. It is passed through the client's
+// name sanitizer, if any. An empty name is an error, and reserved characters
+// are handled according to the client's NameMode.
 // value is a preformatted "raw" value string.
-// rate is the sample rate (0.0 to 1.0).
+// rate is the sample rate (0.0 to 1.0). The client's random number generator
+// is only consulted when rate is less than 1.
+//
+// A rate of 0 or UseDefaultRate uses the client's default rate for the type
+// of the value, as for the other methods. Otherwise a rate that is not
+// greater than 0 and at most 1 is an error, and nothing is sent.
 func (s *Client) Raw(stat string, value string, rate float32) error {
+	if s != nil && (rate == 0 || rate == UseDefaultRate) {
+		rate = s.defaultRateFor(stat, rawType(value), rate)
+	}
+	return s.submit(stat, []byte(value), "", rate)
+}
+
+// RawWithPrefix is as Raw, with prefix in place of the prefix of the client,
+// for a metric in another namespace without creating a client for it. An
+// empty prefix sends the bare stat name. Tags are added as for Raw.
+func (s *Client) RawWithPrefix(prefix, stat, value string, rate float32) error {
+	if s != nil && (rate == 0 || rate == UseDefaultRate) {
+		rate = s.defaultRateFor(stat, rawType(value), rate)
+	}
+	return s.submitWithPrefix(prefix, stat, []byte(value), "", rate)
+}
+
+// IncWithPrefix is as Inc, with prefix in place of the prefix of the client,
+// as for RawWithPrefix.
+func (s *Client) IncWithPrefix(prefix, stat string, value int64, rate float32) error {
+	var b [20]byte
+	return s.submitWithPrefix(prefix, stat, strconv.AppendInt(b[:0], value, 10), "|c", rate)
+}
+
+// RawBytes is as Raw, for callers that hold the stat name and value as byte
+// slices, and sends them without allocating. Neither slice is retained, but
+// a custom name sanitizer is given a string sharing the memory of stat, so
+// it must not retain its argument.
+func (s *Client) RawBytes(stat []byte, value []byte, rate float32) error {
+	if s != nil && (rate == 0 || rate == UseDefaultRate) {
+		rate = s.defaultRateFor(bytesToString(stat), rawType(bytesToString(value)), rate)
+	}
+	return s.submit(bytesToString(stat), value, "", rate)
+}
+
+// bytesToString returns a string sharing the memory of b, which must not be
+// modified while the string is in use.
+func bytesToString(b []byte) string {
+	return unsafe.String(unsafe.SliceData(b), len(b))
+}
+
+// bufPool holds buffers for formatting metrics, to avoid allocating one per
+// metric sent.
+var bufPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 128)
+		return &b
+	},
+}
+
+// submit handles sampling, formats the metric, and sends it.
+// value is the formatted value, and suffix the metric type, such as "|c".
+func (s *Client) submit(stat string, value []byte, suffix string, rate float32) error {
+	stat, rate, ok, err := s.prepare(stat, suffix, rate)
+	if !ok {
+		return err
+	}
+
+	bp := bufPool.Get().(*[]byte)
+	buf := s.appendMetric((*bp)[:0], stat, value, suffix, rate)
+	err = s.sendMetrics(buf, 1)
+	*bp = buf
+	bufPool.Put(bp)
+	return err
+}
+
+// submitWithPrefix is as submit, with prefix in place of the prefix of the
+// client.
+func (s *Client) submitWithPrefix(prefix, stat string, value []byte, suffix string, rate float32) error {
+	stat, rate, ok, err := s.prepare(stat, suffix, rate)
+	if !ok {
+		return err
+	}
+
+	bp := bufPool.Get().(*[]byte)
+	buf := s.appendPrefixedMetric((*bp)[:0], prefix, stat, value, suffix, rate)
+	err = s.sendMetrics(buf, 1)
+	*bp = buf
+	bufPool.Put(bp)
+	return err
+}
+
+// submitNegativeGauge sends a gauge set to the negative value, as a reset to
+// 0 followed by the value in the same packet. A bare negative value would be
+// treated as a decrement by statsd.
+func (s *Client) submitNegativeGauge(stat string, value []byte, rate float32) error {
+	stat, rate, ok, err := s.prepare(stat, "|g", rate)
+	if !ok {
+		return err
+	}
+
+	bp := bufPool.Get().(*[]byte)
+	buf := s.appendMetric((*bp)[:0], stat, []byte("0"), "|g", rate)
+	buf = append(buf, '\n')
+	buf = s.appendMetric(buf, stat, value, "|g", rate)
+	err = s.sendMetrics(buf, 1)
+	*bp = buf
+	bufPool.Put(bp)
+	return err
+}
+
+// prepare applies the default rate for the metric type given by suffix, such
+// as "|c", validates the rate and stat name, and decides whether the metric
+// is sampled in. It returns the stat name and rate to send with, and ok is
+// false if nothing should be sent.
+func (s *Client) prepare(stat string, suffix string, rate float32) (string, float32, bool, error) {
+	stat, rate, ok, err := s.check(stat, suffix, rate)
+	if ok && s.sampledOut(stat, rate) {
+		return stat, rate, false, nil
+	}
+	return stat, rate, ok, err
+}
+
+// check validates and sanitizes a metric before sampling, as prepare. It
+// returns ok as false, with a nil error, for a nil client.
+func (s *Client) check(stat string, suffix string, rate float32) (string, float32, bool, error) {
 	if s == nil {
-		return nil
+		return stat, rate, false, nil
+	}
+	if s.closer.isClosed() {
+		return stat, rate, false, ErrClosed
+	}
+	if rate == 0 || rate == UseDefaultRate {
+		rate = s.defaultRateFor(stat, strings.TrimPrefix(suffix, "|"), rate)
+	}
+	if err := validateRate(rate); err != nil {
+		return stat, rate, false, err
+	}
+	stat, err := s.name(stat)
+	if err != nil {
+		return stat, rate, false, err
+	}
+	return stat, rate, true, nil
+}
+
+// defaultRateFor returns the rate used for stat, a metric of metricType such
+// as "c", when called with a rate of 0 or UseDefaultRate: an override set
+// with SetRateOverride, else the default rate for its type set with
+// WithTypeDefaultRate, else the one set with WithDefaultRate. Without any,
+// UseDefaultRate samples everything, while 0 is returned unchanged to be
+// rejected as invalid.
+func (s *Client) defaultRateFor(stat string, metricType string, rate float32) float32 {
+	if r, ok := s.overrides.lookup(stat); ok {
+		return r
 	}
-	if rate < 1 {
-		if rand.Float32() < rate {
-			value = fmt.Sprintf("%s|@%f", value, rate)
-		} else {
-			return nil
+	if r, ok := s.typeRates[metricType]; ok {
+		return r
+	}
+	if s.defaultRate != 0 {
+		return s.defaultRate
+	}
+	if rate == UseDefaultRate {
+		return 1
+	}
+	return rate
+}
+
+// rawType returns the metric type of a raw value, such as "c" for "1|c".
+func rawType(value string) string {
+	i := strings.IndexByte(value, '|')
+	if i < 0 {
+		return ""
+	}
+	t := value[i+1:]
+	if j := strings.IndexByte(t, '|'); j >= 0 {
+		t = t[:j]
+	}
+	return t
+}
+
+// sampled reports whether a metric for stat at rate is sampled in, with the
+// Sampler of the client if set, and randomly otherwise.
+func (s *Client) sampled(stat string, rate float32) bool {
+	if rate >= 1 {
+		return true
+	}
+	if s.sampler != nil {
+		return s.sampler.ShouldSample(stat, rate)
+	}
+	return s.rng.Float32() < rate
+}
+
+// sampledOut reports whether a metric for stat at rate should be skipped,
+// counting it in SampledOut if so.
+func (s *Client) sampledOut(stat string, rate float32) bool {
+	if !s.sampled(stat, rate) {
+		atomic.AddUint64(&s.stats.sampledOut, 1)
+		return true
+	}
+	return false
+}
+
+// appendMetric appends a single formatted metric line to buf.
+func (s *Client) appendMetric(buf []byte, stat string, value []byte, suffix string, rate float32) []byte {
+	s.prefixMx.RLock()
+	prefix := s.prefix
+	s.prefixMx.RUnlock()
+	return s.appendPrefixedMetric(buf, prefix, stat, value, suffix, rate)
+}
+
+// appendPrefixedMetric is as appendMetric, with prefix in place of the prefix
+// of the client.
+func (s *Client) appendPrefixedMetric(buf []byte, prefix string, stat string, value []byte, suffix string, rate float32) []byte {
+	if prefix != "" {
+		buf = append(buf, prefix...)
+		if !strings.HasSuffix(prefix, s.separator) {
+			buf = append(buf, s.separator...)
 		}
 	}
+	buf = append(buf, stat...)
+	if s.suffix != "" {
+		if !strings.HasPrefix(s.suffix, s.separator) {
+			buf = append(buf, s.separator...)
+		}
+		buf = append(buf, s.suffix...)
+	}
+	if s.tagFormat == TagFormatInflux {
+		buf = append(buf, s.tagString...)
+	}
+	buf = append(buf, ':')
+	buf = append(buf, value...)
+	buf = append(buf, suffix...)
 
-	if s.prefix != "" {
-		stat = fmt.Sprintf("%s.%s", s.prefix, stat)
+	buf = s.appendRate(buf, rate)
+
+	if s.tagFormat == TagFormatDatadog && s.tagString != "" {
+		buf = append(buf, "|#"...)
+		buf = append(buf, s.tagString...)
 	}
+	return buf
+}
 
-	data := fmt.Sprintf("%s:%s", stat, value)
+// appendRate appends the sample rate suffix, for a rate below 1.
+func (s *Client) appendRate(buf []byte, rate float32) []byte {
+	if rate < 1 && !s.noRateSuffix {
+		buf = append(buf, "|@"...)
+		buf = strconv.AppendFloat(buf, float64(rate), 'g', -1, 32)
+	}
+	return buf
+}
 
-	_, err := s.sender.Send([]byte(data))
+// sendMetrics sends formatted data holding n metrics, counting the result and
+// passing any error to the error hook.
+func (s *Client) sendMetrics(data []byte, n uint64) error {
+	if s.newline {
+		data = append(data, '\n')
+	}
+	if s.maxPacketSize > 0 && len(data) > s.maxPacketSize {
+		err := fmt.Errorf("%w: %d bytes exceeds the maximum packet size of %d bytes", ErrPacketTooLarge, len(data), s.maxPacketSize)
+		atomic.AddUint64(&s.stats.errors, n)
+		if s.onError != nil {
+			s.onError(err)
+		}
+		return err
+	}
+	if s.logger != nil {
+		s.logger(data)
+	}
+	_, err := s.send(data)
+	if errors.Is(err, ErrRateLimited) || errors.Is(err, ErrQueueFull) {
+		// counted by the sender in Dropped
+		if s.onError != nil {
+			s.onError(err)
+		}
+		return nil
+	}
 	if err != nil {
+		atomic.AddUint64(&s.stats.errors, n)
+		if s.onError != nil {
+			s.onError(err)
+		}
 		return err
 	}
+	atomic.AddUint64(&s.stats.sent, n)
+	atomic.AddUint64(&s.stats.bytesSent, uint64(len(data)))
+	return nil
+}
+
+// send sends data via the sender, honoring the client context if one was set
+// with WithContext.
+func (s *Client) send(data []byte) (int, error) {
+	if s.ctx == nil {
+		return s.sender.Send(data)
+	}
+	if err := s.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if cs, ok := s.sender.(contextSender); ok {
+		return cs.SendContext(s.ctx, data)
+	}
+	return s.sender.Send(data)
+}
+
+// RateOneIn returns the sample rate for sending one in n metrics, such as
+// RateOneIn(10) for 0.1, so that call sites state the sampling plainly. An n
+// less than 1 returns a rate of 1, sending everything; use IncEvery, which
+// returns an error instead, to reject such values of n.
+func RateOneIn(n int) float32 {
+	if n <= 1 {
+		return 1
+	}
+	return float32(1.0 / float64(n))
+}
+
+// checkOneIn returns an error if n is less than 1.
+func checkOneIn(n int) error {
+	if n < 1 {
+		return fmt.Errorf("Invalid sampling of one in %d, must be at least 1", n)
+	}
+	return nil
+}
+
+// IncEvery increments a statsd count type, sampled at one in n, as Inc with
+// RateOneIn(n). An n less than 1 is an error, and nothing is sent.
+func (s *Client) IncEvery(stat string, value int64, n int) error {
+	if err := checkOneIn(n); err != nil {
+		return err
+	}
+	return s.Inc(stat, value, RateOneIn(n))
+}
+
+// TimingEvery submits a statsd timing type, sampled at one in n, as
+// TimingDuration with RateOneIn(n). An n less than 1 is an error, and nothing
+// is sent.
+func (s *Client) TimingEvery(stat string, delta time.Duration, n int) error {
+	if err := checkOneIn(n); err != nil {
+		return err
+	}
+	return s.TimingDuration(stat, delta, RateOneIn(n))
+}
+
+// validateRate returns an error if rate is not a usable sample rate, in the
+// range (0.0, 1.0].
+func validateRate(rate float32) error {
+	if rate <= 0 || rate > 1 {
+		return fmt.Errorf("Invalid sample rate %v, must be greater than 0 and at most 1", rate)
+	}
 	return nil
 }
 
 // Sets/Updates the statsd client prefix.
+// It is safe to call while other goroutines are sending.
 func (s *Client) SetPrefix(prefix string) {
 	if s == nil {
 		return
 	}
+	s.prefixMx.Lock()
 	s.prefix = prefix
+	s.prefixMx.Unlock()
+}
+
+// getPrefix returns the statsd client prefix.
+func (s *Client) getPrefix() string {
+	s.prefixMx.RLock()
+	defer s.prefixMx.RUnlock()
+	return s.prefix
+}
+
+// Sets the source of randomness used for sampling, for example to make
+// sampling reproducible in tests. Clients derived with WithTags share the
+// new source.
+// By default, a source seeded with the client creation time is used.
+func (s *Client) SetRandSource(src rand.Source) {
+	if s == nil {
+		return
+	}
+	s.rng.mx.Lock()
+	s.rng.r = rand.New(src)
+	s.rng.mx.Unlock()
+}
+
+// WithTags returns a new Statter that shares this client's sender and
+// prefix, and appends the supplied DogStatsD tags to every metric, after any
+// sample rate. Tags are added to any the client already has, such as those
+// set at construction; a tag with the same key as an existing tag replaces
+// its value rather than being repeated.
+// Commas and pipes are stripped from tag keys and values.
+func (s *Client) WithTags(tags ...Tag) Statter {
+	if s == nil {
+		return s
+	}
+	c := s.derive()
+	c.tags = mergeTags(s.tags, tags)
+	c.tagString = formatTags(c.tags, c.tagFormat)
+	return c
+}
+
+// NewSubStatter returns a new Statter that shares this client's sender, with
+// prefix appended to this client's prefix (separated by the prefix separator,
+// "." by default). Changing the prefix of either client does not affect the
+// other.
+func (s *Client) NewSubStatter(prefix string) Statter {
+	if s == nil {
+		return s
+	}
+	c := s.derive()
+	switch {
+	case c.prefix == "":
+		c.prefix = prefix
+	case prefix != "":
+		if !strings.HasSuffix(c.prefix, c.separator) {
+			c.prefix += c.separator
+		}
+		c.prefix += prefix
+	}
+	return c
+}
+
+// WithPrefix returns a copy of the client that shares its sender, such as
+// for per tenant clients sharing one socket, with prefix replacing this
+// client's prefix, and with its own Stats counts. The Dropped count of the
+// sender is still shared. Like other derived clients, closing the copy does
+// nothing, and it returns ErrClosed once this client is closed.
+func (s *Client) WithPrefix(prefix string) *Client {
+	if s == nil {
+		return s
+	}
+	c := s.derive()
+	c.prefix = prefix
+	c.stats = &clientStats{}
+	return c
+}
+
+// WithContext returns a new Statter that shares this client's sender, and
+// returns ctx.Err() instead of sending once ctx is done.
+//
+// For stream senders, such as TCPSender, the deadline and cancellation of ctx
+// also apply to a write that is in progress. Datagram and buffered senders do
+// not block on writes, so for them ctx is only checked before sending.
+func (s *Client) WithContext(ctx context.Context) Statter {
+	if s == nil {
+		return s
+	}
+	c := s.derive()
+	c.ctx = ctx
+	return c
+}
+
+// derive returns a copy of the client that shares its sender and rng.
+func (s *Client) derive() *Client {
+	return &Client{
+		prefix:          s.getPrefix(),
+		separator:       s.separator,
+		suffix:          s.suffix,
+		nameMode:        s.nameMode,
+		sanitizer:       s.sanitizer,
+		names:           s.names,
+		sender:          s.sender,
+		tags:            s.tags,
+		tagString:       s.tagString,
+		tagFormat:       s.tagFormat,
+		rng:             s.rng,
+		sampler:         s.sampler,
+		defaultRate:     s.defaultRate,
+		typeRates:       s.typeRates,
+		overrides:       s.overrides,
+		derived:         true,
+		ctx:             s.ctx,
+		stats:           s.stats,
+		closer:          s.closer,
+		onError:         s.onError,
+		logger:          s.logger,
+		maxPacketSize:   s.maxPacketSize,
+		newline:         s.newline,
+		noRateSuffix:    s.noRateSuffix,
+		recorder:        s.recorder,
+		shutdownStat:    s.shutdownStat,
+		clock:           s.clock,
+		roundTimings:    s.roundTimings,
+		timingPrecision: s.timingPrecision,
+		zeroTimings:     s.zeroTimings,
+		emissions:       s.emissions,
+		emitInterval:    s.emitInterval,
+		counts:          s.counts,
+		gaugeDeltas:     s.gaugeDeltas,
+	}
 }
 
 // SimpleSender provides a socket send interface.
@@ -141,15 +1432,37 @@ type SimpleSender struct {
 	c net.PacketConn
 	// resolved udp address
 	ra *net.UDPAddr
+	// deadline applied to each write, if non-zero
+	writeTimeout time.Duration
+	// if borrowed, Close leaves c open and sets closed to 1 instead
+	borrowed bool
+	closed   uint32
 }
 
 // Send sends the data to the server endpoint.
+//
+// If the socket send buffer is full, as reported by ENOBUFS or EAGAIN, the
+// write is retried once after a short pause. This is best-effort: if the
+// buffer is still full the data is dropped, and the error returned matches
+// ErrSendBufferFull, to be counted in Stats and passed to the error hook by
+// the client.
 func (s *SimpleSender) Send(data []byte) (int, error) {
+	if s.borrowed && atomic.LoadUint32(&s.closed) == 1 {
+		return 0, ErrClosed
+	}
+	if s.writeTimeout > 0 {
+		s.c.SetWriteDeadline(time.Now().Add(s.writeTimeout))
+	}
 	// no need for locking here, as the underlying fdNet
 	// already serialized writes
-	n, err := s.c.(*net.UDPConn).WriteToUDP(data, s.ra)
+	n, err := retryBufferFull(func() (int, error) {
+		if c, ok := s.c.(*net.UDPConn); ok {
+			return c.WriteToUDP(data, s.ra)
+		}
+		return s.c.WriteTo(data, s.ra)
+	})
 	if err != nil {
-		return 0, err
+		return 0, udpErr(err, len(data), s.ra)
 	}
 	if n == 0 {
 		return n, errors.New("Wrote no bytes")
@@ -157,10 +1470,25 @@ func (s *SimpleSender) Send(data []byte) (int, error) {
 	return n, nil
 }
 
-// Closes SimpleSender
+// RemoteAddr returns the resolved address data is sent to.
+func (s *SimpleSender) RemoteAddr() net.Addr {
+	return s.ra
+}
+
+// Reconnect does nothing, as the socket is not connected to the server.
+func (s *SimpleSender) Reconnect() error {
+	return nil
+}
+
+// Closes SimpleSender, and its connection unless it was borrowed with
+// NewSenderFromConn.
+// Later calls return nil, and Send returns ErrClosed once closed.
 func (s *SimpleSender) Close() error {
-	err := s.c.Close()
-	return err
+	if s.borrowed {
+		atomic.StoreUint32(&s.closed, 1)
+		return nil
+	}
+	return closeConn(s.c)
 }
 
 // Returns a new SimpleSender for sending to the supplied addresss.
@@ -170,12 +1498,42 @@ func (s *SimpleSender) Close() error {
 func NewSimpleSender(addr string) (Sender, error) {
 	c, err := net.ListenPacket("udp", ":0")
 	if err != nil {
-		return nil, err
+		return nil, listenErr(err)
 	}
 
 	ra, err := net.ResolveUDPAddr("udp", addr)
 	if err != nil {
-		return nil, err
+		c.Close()
+		return nil, resolveErr(addr, err)
+	}
+
+	sender := &SimpleSender{
+		c:  c,
+		ra: ra,
+	}
+
+	return sender, nil
+}
+
+// Returns a new SimpleSender for sending to remoteAddr from localAddr, such as
+// to send from a specific interface of a multi-homed host.
+//
+// localAddr and remoteAddr are strings of the format "host:port", and must be
+// parsable by net.ResolveUDPAddr. A port of 0 in localAddr picks any free
+// port. An error binding localAddr matches ErrListen.
+func NewSimpleSenderBound(localAddr, remoteAddr string) (Sender, error) {
+	la, err := net.ResolveUDPAddr("udp", localAddr)
+	if err != nil {
+		return nil, resolveErr(localAddr, err)
+	}
+	ra, err := net.ResolveUDPAddr("udp", remoteAddr)
+	if err != nil {
+		return nil, resolveErr(remoteAddr, err)
+	}
+
+	c, err := net.ListenUDP("udp", la)
+	if err != nil {
+		return nil, listenErr(err)
 	}
 
 	sender := &SimpleSender{
@@ -186,6 +1544,49 @@ func NewSimpleSender(addr string) (Sender, error) {
 	return sender, nil
 }
 
+// Returns a new SimpleSender for sending to remoteAddr with an existing
+// connection, such as one managed by a socket manager, in place of one
+// created with net.ListenPacket. The connection is borrowed: Close does not
+// close it, and it may be shared with other senders. Use NewSenderOwningConn
+// for Close to also close the connection.
+//
+// c must not be connected to an address, as with one from net.ListenPacket.
+// remoteAddr is a string of the format "hostname:port", and must be parsable
+// by net.ResolveUDPAddr.
+func NewSenderFromConn(c net.PacketConn, remoteAddr string) (Sender, error) {
+	ra, err := net.ResolveUDPAddr("udp", remoteAddr)
+	if err != nil {
+		return nil, resolveErr(remoteAddr, err)
+	}
+	return &SimpleSender{c: c, ra: ra, borrowed: true}, nil
+}
+
+// Returns a new SimpleSender as NewSenderFromConn, that takes ownership of
+// c, closing it when closed.
+func NewSenderOwningConn(c net.PacketConn, remoteAddr string) (Sender, error) {
+	ra, err := net.ResolveUDPAddr("udp", remoteAddr)
+	if err != nil {
+		return nil, resolveErr(remoteAddr, err)
+	}
+	return &SimpleSender{c: c, ra: ra}, nil
+}
+
+// Returns a new SimpleSender for sending to the supplied addresss, where
+// each Send returns an error if the write does not complete within timeout.
+//
+// addr is a string of the format "hostname:port", and must be parsable by
+// net.ResolveUDPAddr.
+//
+// If timeout is 0, writes have no deadline, as with NewSimpleSender.
+func NewSimpleSenderWithTimeout(addr string, timeout time.Duration) (Sender, error) {
+	sender, err := NewSimpleSender(addr)
+	if err != nil {
+		return nil, err
+	}
+	sender.(*SimpleSender).writeTimeout = timeout
+	return sender, nil
+}
+
 // Returns a pointer to a new Client, and an error.
 //
 // addr is a string of the format "hostname:port", and must be parsable by
@@ -198,10 +1599,7 @@ func NewClient(addr, prefix string) (Statter, error) {
 		return nil, err
 	}
 
-	client := &Client{
-		prefix: prefix,
-		sender: sender,
-	}
+	client := newClient(sender, prefix)
 
 	return client, nil
 }
@@ -2,9 +2,16 @@ package statsd
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"log"
+	"math"
 	"net"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -23,9 +30,24 @@ var statsdPacketTests = []struct {
 	{"test", "Dec", "count", int64(1), 1.0, "test.count:-1|c"},
 	{"test", "Timing", "timing", int64(1), 1.0, "test.timing:1|ms"},
 	{"test", "TimingDuration", "timing", time.Microsecond * 1500, 1.0, "test.timing:1.50|ms"},
+	{"test", "TimingGauge", "lat", time.Microsecond * 12500, 1.0, "test.lat:12.50|g"},
 	{"", "Inc", "count", int64(1), 1.0, "count:1|c"},
 	{"", "GaugeDelta", "gauge", int64(1), 1.0, "gauge:+1|g"},
 	{"", "GaugeDelta", "gauge", int64(-1), 1.0, "gauge:-1|g"},
+	{"", "Set", "mystat", "someuser", 1.0, "mystat:someuser|s"},
+	{"", "GaugeFloat", "gauge", 0.75, 1.0, "gauge:0.75|g"},
+	{"", "GaugeFloat", "gauge", float64(12345678), 1.0, "gauge:12345678|g"},
+	{"", "GaugeUint64", "gauge", uint64(math.MaxUint64), 1.0, "gauge:18446744073709551615|g"},
+	{"", "GaugeTime", "last", time.Unix(1700000000, 5e8), 1.0, "last:1700000000|g"},
+	{"", "GaugeTimeMillis", "last", time.Unix(1700000000, 5e8), 1.0, "last:1700000000500|g"},
+	{"test", "Gauge", "gauge", int64(-5), 1.0, "test.gauge:0|g\ntest.gauge:-5|g"},
+	{"test", "GaugeFloat", "gauge", -0.5, 1.0, "test.gauge:0|g\ntest.gauge:-0.5|g"},
+	{"", "GaugeDeltaFloat", "gauge", 1.5, 1.0, "gauge:+1.5|g"},
+	{"", "GaugeDeltaFloat", "gauge", -0.25, 1.0, "gauge:-0.25|g"},
+	{"test", "Histogram", "hist", int64(512), 1.0, "test.hist:512|h"},
+	{"test", "HistogramFloat", "hist", 1.25, 1.0, "test.hist:1.25|h"},
+	{"", "Distribution", "dist", 3.14, 1.0, "dist:3.14|d"},
+	{"test", "Meter", "meter", int64(3), 1.0, "test.meter:3|m"},
 }
 
 func TestClient(t *testing.T) {
@@ -65,6 +87,401 @@ func TestClient(t *testing.T) {
 	}
 }
 
+func TestClientWireFormat(t *testing.T) {
+	for _, tt := range statsdPacketTests {
+		rs := NewRecordingSender()
+		c, err := NewClientWithOptions(WithSender(rs), WithPrefix(tt.Prefix))
+		if err != nil {
+			t.Fatal(err)
+		}
+		method := reflect.ValueOf(c).MethodByName(tt.Method)
+		e := method.Call([]reflect.Value{
+			reflect.ValueOf(tt.Stat),
+			reflect.ValueOf(tt.Value),
+			reflect.ValueOf(tt.Rate)})[0]
+		errInter := e.Interface()
+		if errInter != nil {
+			t.Fatal(errInter.(error))
+		}
+
+		sent := rs.GetSent()
+		if len(sent) != 1 || !bytes.Equal(sent[0], []byte(tt.Expected)) {
+			t.Fatalf("%s got '%s' expected '%s'", tt.Method, sent, tt.Expected)
+		}
+	}
+}
+
+var sampledPacketTests = []struct {
+	Method   string
+	Value    interface{}
+	Expected string
+}{
+	{"Histogram", int64(512), "stat:512|h|@0.6"},
+	{"HistogramFloat", 1.25, "stat:1.25|h|@0.6"},
+	{"Distribution", 3.14, "stat:3.14|d|@0.6"},
+}
+
+func TestClientSampled(t *testing.T) {
+	for _, tt := range sampledPacketTests {
+		rs := NewRecordingSender()
+		c, err := NewClientWithOptions(
+			WithSender(rs),
+			// Float32() of this source is always 0.5
+			WithRandSource(constSource(1<<62)),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		method := reflect.ValueOf(c).MethodByName(tt.Method)
+		e := method.Call([]reflect.Value{
+			reflect.ValueOf("stat"),
+			reflect.ValueOf(tt.Value),
+			reflect.ValueOf(float32(0.6))})[0]
+		errInter := e.Interface()
+		if errInter != nil {
+			t.Fatal(errInter.(error))
+		}
+
+		sent := rs.GetSent()
+		if len(sent) != 1 || !bytes.Equal(sent[0], []byte(tt.Expected)) {
+			t.Fatalf("%s got '%s' expected '%s'", tt.Method, sent, tt.Expected)
+		}
+	}
+}
+
+func TestClientSampleRateFormat(t *testing.T) {
+	l, err := newUDPListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	c, err := NewClient(l.LocalAddr().String(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// send enough that at least one is sampled in
+	for i := 0; i < 500; i++ {
+		err = c.Inc("x", 1, 0.1)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data := make([]byte, 128)
+	n, _, err := l.ReadFrom(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "x:1|c|@0.1"
+	if string(data[:n]) != expected {
+		t.Fatalf("got '%s' expected '%s'", data[:n], expected)
+	}
+}
+
+// constSource is a rand.Source that always returns the same value.
+type constSource int64
+
+func (c constSource) Int63() int64 { return int64(c) }
+func (c constSource) Seed(int64)   {}
+
+func TestClientRandSource(t *testing.T) {
+	l, err := newUDPListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	c, err := NewClient(l.LocalAddr().String(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// Float32() of this source is always 0.5
+	c.(*Client).SetRandSource(constSource(1 << 62))
+
+	// sampled out
+	err = c.Inc("x", 1, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// sampled in
+	err = c.Inc("y", 1, 0.6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := make([]byte, 128)
+	n, _, err := l.ReadFrom(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "y:1|c|@0.6"
+	if string(data[:n]) != expected {
+		t.Fatalf("got '%s' expected '%s'", data[:n], expected)
+	}
+}
+
+var sampleRateTests = []struct {
+	Method   string
+	Value    interface{}
+	Expected string
+}{
+	{"Inc", int64(1), "test.stat:1|c|@0.5"},
+	{"Dec", int64(1), "test.stat:-1|c|@0.5"},
+	{"Gauge", int64(1), "test.stat:1|g|@0.5"},
+	{"GaugeDelta", int64(-1), "test.stat:-1|g|@0.5"},
+	{"Timing", int64(1), "test.stat:1|ms|@0.5"},
+	{"TimingDuration", time.Microsecond * 1500, "test.stat:1.50|ms|@0.5"},
+}
+
+func TestClientSampleRate(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithPrefix("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for _, tt := range sampleRateTests {
+		method := reflect.ValueOf(c).MethodByName(tt.Method)
+		send := func() {
+			e := method.Call([]reflect.Value{
+				reflect.ValueOf("stat"),
+				reflect.ValueOf(tt.Value),
+				reflect.ValueOf(float32(0.5))})[0]
+			if err, _ := e.Interface().(error); err != nil {
+				t.Fatal(tt.Method, err)
+			}
+		}
+
+		// Float32() of this source is always 0, so everything is sampled in
+		c.(*Client).SetRandSource(constSource(0))
+		rs.Clear()
+		send()
+		sent := rs.GetSent()
+		if len(sent) != 1 || string(sent[0]) != tt.Expected {
+			t.Fatalf("%s got '%s' expected '%s'", tt.Method, sent, tt.Expected)
+		}
+
+		// Float32() of this source is always 0.5, so a rate of 0.5 is sampled
+		// out
+		c.(*Client).SetRandSource(constSource(1 << 62))
+		rs.Clear()
+		sampledOut := c.Stats().SampledOut
+		send()
+		if sent := rs.GetSent(); len(sent) != 0 {
+			t.Fatalf("%s got '%s' expected nothing sent", tt.Method, sent)
+		}
+		if c.Stats().SampledOut != sampledOut+1 {
+			t.Fatalf("%s was not counted as sampled out", tt.Method)
+		}
+	}
+}
+
+var subStatterTests = []struct {
+	Prefix    string
+	SubPrefix string
+	Expected  string
+}{
+	{"test", "sub", "test.sub.count:1|c"},
+	{"", "sub", "sub.count:1|c"},
+	{"test", "", "test.count:1|c"},
+}
+
+func TestSubStatter(t *testing.T) {
+	l, err := newUDPListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	for _, tt := range subStatterTests {
+		c, err := NewClient(l.LocalAddr().String(), tt.Prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sub := c.NewSubStatter(tt.SubPrefix)
+		// closing the child must leave the shared sender open
+		sub.Close()
+		err = sub.Inc("count", 1, 1.0)
+		if err != nil {
+			c.Close()
+			t.Fatal(err)
+		}
+
+		data := make([]byte, 128)
+		n, _, err := l.ReadFrom(data)
+		if err != nil {
+			c.Close()
+			t.Fatal(err)
+		}
+		if string(data[:n]) != tt.Expected {
+			c.Close()
+			t.Fatalf("got '%s' expected '%s'", data[:n], tt.Expected)
+		}
+
+		// the parent prefix is unchanged
+		err = c.Inc("count", 1, 1.0)
+		if err != nil {
+			c.Close()
+			t.Fatal(err)
+		}
+		n, _, err = l.ReadFrom(data)
+		if err != nil {
+			c.Close()
+			t.Fatal(err)
+		}
+		expected := "count:1|c"
+		if tt.Prefix != "" {
+			expected = tt.Prefix + "." + expected
+		}
+		if string(data[:n]) != expected {
+			c.Close()
+			t.Fatalf("got '%s' expected '%s'", data[:n], expected)
+		}
+		c.Close()
+	}
+}
+
+var validateRateTests = []struct {
+	Rate  float32
+	Valid bool
+}{
+	{0, false},
+	{-0.5, false},
+	{0.5, true},
+	{1.0, true},
+	{1.5, false},
+}
+
+func TestValidateRate(t *testing.T) {
+	for _, tt := range validateRateTests {
+		err := validateRate(tt.Rate)
+		if (err == nil) != tt.Valid {
+			t.Fatalf("validateRate(%v) got err '%v' expected valid %v", tt.Rate, err, tt.Valid)
+		}
+	}
+}
+
+func TestClientInvalidRate(t *testing.T) {
+	l, err := newUDPListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	c, err := NewClient(l.LocalAddr().String(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for _, tt := range validateRateTests {
+		if tt.Valid {
+			continue
+		}
+		err = c.Inc("count", 1, tt.Rate)
+		if err == nil {
+			t.Fatalf("Inc with rate %v expected an error", tt.Rate)
+		}
+	}
+
+	data := make([]byte, 128)
+	n, _, err := l.ReadFrom(data)
+	if err == nil || n != 0 {
+		t.Fatal("metric with an invalid rate was sent")
+	}
+}
+
+func TestClientWithContextCancelled(t *testing.T) {
+	l, err := newUDPListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	c, err := NewClient(l.LocalAddr().String(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = c.WithContext(ctx).Inc("count", 1, 1.0)
+	if err != context.Canceled {
+		t.Fatalf("got error '%v' expected '%v'", err, context.Canceled)
+	}
+
+	data := make([]byte, 128)
+	n, _, err := l.ReadFrom(data)
+	if err == nil || n != 0 {
+		t.Fatal("metric sent with a cancelled context")
+	}
+}
+
+func TestClientSetPrefixConcurrent(t *testing.T) {
+	c, err := NewClientWithOptions(WithSender(discardSender{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	done := make(chan bool)
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			c.Inc("count", 1, 1.0)
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		c.SetPrefix("test")
+	}
+	<-done
+}
+
+func TestSimpleSenderWriteTimeout(t *testing.T) {
+	l, err := newUDPListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	s, err := NewSimpleSenderWithTimeout(l.LocalAddr().String(), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	_, err = s.Send([]byte("test.count:1|c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := make([]byte, 128)
+	n, _, err := l.ReadFrom(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data[:n]) != "test.count:1|c" {
+		t.Fatalf("got '%s' expected 'test.count:1|c'", data[:n])
+	}
+
+	// a timeout that has always passed by the time of the write
+	s.(*SimpleSender).writeTimeout = time.Nanosecond
+	_, err = s.Send([]byte("test.count:1|c"))
+	if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+		t.Fatalf("got error '%v' expected a timeout", err)
+	}
+}
+
 func TestNilClient(t *testing.T) {
 	l, err := newUDPListener("127.0.0.1:0")
 	if err != nil {
@@ -92,6 +509,9 @@ func TestNilClient(t *testing.T) {
 			c.Close()
 			t.Fatal(err)
 		}
+		if err := c.Flush(); err != nil {
+			t.Fatal(err)
+		}
 		c.Close()
 	}
 }
@@ -129,6 +549,81 @@ func TestNoopClient(t *testing.T) {
 	}
 }
 
+func TestNoopClientExtras(t *testing.T) {
+	// the arguments to NewNoopClient are ignored, so the sender is not used
+	rs := NewRecordingSender()
+	c, err := NewNoopClient(WithSender(rs))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statters := map[string]Statter{
+		"NoopClient":    c,
+		"WithTags":      c.WithTags(Tag{"a", "1"}),
+		"NewSubStatter": c.NewSubStatter("sub"),
+		"WithContext":   c.WithContext(context.Background()),
+	}
+	for name, s := range statters {
+		callAll(t, name, reflect.ValueOf(s), 2)
+		if err := s.NewBatch().Submit(); err != nil {
+			t.Fatalf("%s NewBatch().Submit() got error '%v' expected nil", name, err)
+		}
+	}
+
+	if sent := rs.GetSent(); len(sent) != 0 {
+		t.Fatalf("got '%s' expected NoopClient to send nothing", sent)
+	}
+}
+
+// callAll calls every exported method of v with placeholder arguments,
+// failing the test for any error returned, and calls the methods of the
+// values returned, such as a Statter or Batch, up to depth levels down.
+func callAll(t *testing.T, name string, v reflect.Value, depth int) {
+	t.Helper()
+	for i := 0; i < v.NumMethod(); i++ {
+		m := v.Method(i)
+		mt := m.Type()
+		method := name + "." + v.Type().Method(i).Name
+
+		n := mt.NumIn()
+		if mt.IsVariadic() {
+			n--
+		}
+		args := make([]reflect.Value, n)
+		for j := range args {
+			switch in := mt.In(j); in.Kind() {
+			case reflect.String:
+				args[j] = reflect.ValueOf("noop_test").Convert(in)
+			case reflect.Float32, reflect.Float64, reflect.Int, reflect.Int64, reflect.Uint64:
+				// such as a rate and a window that are valid
+				args[j] = reflect.ValueOf(1).Convert(in)
+			case reflect.Func:
+				args[j] = reflect.MakeFunc(in, func([]reflect.Value) []reflect.Value {
+					out := make([]reflect.Value, in.NumOut())
+					for k := range out {
+						out[k] = reflect.Zero(in.Out(k))
+					}
+					return out
+				})
+			default:
+				args[j] = reflect.Zero(in)
+			}
+		}
+
+		for _, out := range m.Call(args) {
+			if err, ok := out.Interface().(error); ok && err != nil {
+				t.Fatalf("%s got error '%v' expected nil", method, err)
+			}
+			switch out.Kind() {
+			case reflect.Interface, reflect.Ptr, reflect.Struct:
+				if depth > 0 && out.Type() != reflect.TypeOf((*error)(nil)).Elem() && !out.IsZero() {
+					callAll(t, method, out, depth-1)
+				}
+			}
+		}
+	}
+}
+
 func newUDPListener(addr string) (*net.UDPConn, error) {
 	l, err := net.ListenPacket("udp", addr)
 	if err != nil {
@@ -182,3 +677,750 @@ func ExampleClient_noop() {
 		log.Printf("Error sending metric: %+v", err)
 	}
 }
+
+// discardSender is a Sender that discards all data.
+type discardSender struct{}
+
+func (discardSender) Send(data []byte) (int, error) { return len(data), nil }
+func (discardSender) Close() error                  { return nil }
+
+func BenchmarkClientInc(b *testing.B) {
+	c, _ := NewClientWithOptions(WithSender(discardSender{}), WithPrefix("test"))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Inc("count", 1, 1.0)
+	}
+}
+
+func BenchmarkClientTimingDuration(b *testing.B) {
+	c, _ := NewClientWithOptions(WithSender(discardSender{}), WithPrefix("test"))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.TimingDuration("timing", 1500*time.Microsecond, 1.0)
+	}
+}
+
+func BenchmarkClientRawSampledTagged(b *testing.B) {
+	c, _ := NewClientWithOptions(WithSender(discardSender{}), WithPrefix("test"))
+	c = c.WithTags(Tag{"env", "prod"})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Raw("raw", "1|c", 0.999999)
+	}
+}
+
+func BenchmarkClientRaw(b *testing.B) {
+	c, _ := NewClientWithOptions(WithSender(discardSender{}), WithPrefix("test"))
+	stat, value := []byte("raw"), []byte("1|c")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Raw(string(stat), string(value), 1.0)
+	}
+}
+
+func BenchmarkClientRawBytes(b *testing.B) {
+	c, _ := NewClientWithOptions(WithSender(discardSender{}), WithPrefix("test"))
+	stat, value := []byte("raw"), []byte("1|c")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.RawBytes(stat, value, 1.0)
+	}
+}
+
+func TestClientRawBytes(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithPrefix("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	err = c.RawBytes([]byte("raw"), []byte("1|c"), 1.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := rs.GetSent()
+	if len(sent) != 1 || string(sent[0]) != "test.raw:1|c" {
+		t.Fatalf("got '%s' expected 'test.raw:1|c'", sent)
+	}
+
+	if err := c.RawBytes(nil, []byte("1|c"), 1.0); err == nil {
+		t.Fatal("expected an error for an empty stat name")
+	}
+}
+
+func TestClientSuffix(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithPrefix("prefix"), WithSuffix("us_east"),
+		WithTags(Tag{Key: "k", Value: "v"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.Inc("stat", 1, 1.0)
+	c.TimingMulti("timing", []time.Duration{time.Millisecond, time.Millisecond}, 1.0)
+	c.NewSubStatter("sub").Gauge("gauge", 2, 1.0)
+
+	expected := []string{
+		"prefix.stat.us_east:1|c|#k:v",
+		"prefix.timing.us_east:1.00|ms:1.00|ms|#k:v",
+		"prefix.sub.gauge.us_east:2|g|#k:v",
+	}
+	sent := rs.GetSent()
+	if len(sent) != len(expected) {
+		t.Fatalf("got '%s' expected '%s'", sent, expected)
+	}
+	for i, e := range expected {
+		if string(sent[i]) != e {
+			t.Fatalf("got '%s' expected '%s'", sent[i], e)
+		}
+	}
+}
+
+func TestClientRawWithPrefix(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithPrefix("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.(*Client).RawWithPrefix("_internal", "raw", "1|c", 1.0); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.(*Client).RawWithPrefix("", "raw", "1|c", 1.0); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.(*Client).IncWithPrefix("other.", "count", 2, 1.0); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Inc("count", 1, 1.0); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"_internal.raw:1|c", "raw:1|c", "other.count:2|c", "test.count:1|c"}
+	sent := rs.GetSent()
+	if len(sent) != len(expected) {
+		t.Fatalf("got '%s' expected '%s'", sent, expected)
+	}
+	for i, e := range expected {
+		if string(sent[i]) != e {
+			t.Fatalf("got '%s' expected '%s'", sent[i], e)
+		}
+	}
+}
+
+func TestClientCustom(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithPrefix("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.Custom("custom", "1", "kv", 1.0); err != nil {
+		t.Fatal(err)
+	}
+	b := c.NewBatch()
+	b.Custom("custom", "2", "kv", 1.0)
+	if err := b.Submit(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"test.custom:1|kv", "test.custom:2|kv"}
+	sent := rs.GetSent()
+	if len(sent) != len(expected) {
+		t.Fatalf("got '%s' expected '%s'", sent, expected)
+	}
+	for i, e := range expected {
+		if string(sent[i]) != e {
+			t.Fatalf("got '%s' expected '%s'", sent[i], e)
+		}
+	}
+
+	for _, suffix := range []string{"", "k|v", "k:v", "k@v", "k#v", "k\nv"} {
+		if err := c.Custom("custom", "1", suffix, 1.0); err == nil {
+			t.Fatalf("expected an error for metric type %q", suffix)
+		}
+	}
+}
+
+func TestClientGaugeDeltaZero(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithPrefix("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// "test.gauge:+0|g" would set the gauge to 0 on some servers
+	if err := c.GaugeDelta("gauge", 0, 1.0); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.GaugeDeltaFloat("gauge", 0, 1.0); err != nil {
+		t.Fatal(err)
+	}
+	b := c.NewBatch()
+	b.GaugeDelta("gauge", 0, 1.0)
+	b.GaugeDeltaFloat("gauge", 0, 1.0)
+	if b.Len() != 0 {
+		t.Fatalf("got a batch of %d bytes expected an empty batch", b.Len())
+	}
+	if sent := rs.GetSent(); len(sent) != 0 {
+		t.Fatalf("got '%s' expected nothing sent", sent)
+	}
+}
+
+func TestClientCloseTwice(t *testing.T) {
+	l, err := newUDPListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	c, err := NewClient(l.LocalAddr().String(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := c.NewSubStatter("sub")
+
+	err = c.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Close()
+	if err != nil {
+		t.Fatalf("second Close got error '%v' expected nil", err)
+	}
+
+	for _, s := range []Statter{c, sub} {
+		err = s.Inc("count", 1, 1.0)
+		if err != ErrClosed {
+			t.Fatalf("got error '%v' expected '%v'", err, ErrClosed)
+		}
+	}
+}
+
+func TestSendersRemoteAddr(t *testing.T) {
+	l, err := newUDPListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	addr := l.LocalAddr().String()
+
+	tl, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Close()
+
+	senders := map[string]func() (Sender, error){
+		"SimpleSender":   func() (Sender, error) { return NewSimpleSender(addr) },
+		"BufferedSender": func() (Sender, error) { return NewBufferedSender(addr, time.Hour, 0) },
+		"TCPSender":      func() (Sender, error) { return NewTCPSender(tl.Addr().String()) },
+		"PooledSender":   func() (Sender, error) { return NewPooledSender(addr, 2) },
+	}
+	expected := map[string]string{
+		"SimpleSender":   addr,
+		"BufferedSender": addr,
+		"TCPSender":      tl.Addr().String(),
+		"PooledSender":   addr,
+	}
+
+	for name, newSender := range senders {
+		s, err := newSender()
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		ra := s.(Addressable).RemoteAddr()
+		if ra == nil || ra.String() != expected[name] {
+			t.Fatalf("%s got remote address %v expected %s", name, ra, expected[name])
+		}
+		s.Close()
+	}
+}
+
+func TestClientReconnect(t *testing.T) {
+	l, err := newUDPListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	c, err := NewClient(l.LocalAddr().String(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// SimpleSender implements Reconnect as a no-op
+	if err := c.(*Client).Reconnect(); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Close()
+	if err := c.(*Client).Reconnect(); err != ErrClosed {
+		t.Fatalf("got error '%v' expected '%v'", err, ErrClosed)
+	}
+}
+
+func TestClientPing(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithPrefix("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.(*Client).Ping(); err != nil {
+		t.Fatal(err)
+	}
+	sent := rs.GetSent()
+	if len(sent) != 1 || string(sent[0]) != "test.health.ping:0|c" {
+		t.Fatalf("got '%s' expected 'test.health.ping:0|c'", sent)
+	}
+
+	c.Close()
+	if err := c.(*Client).Ping(); err != ErrClosed {
+		t.Fatalf("got error '%v' expected '%v'", err, ErrClosed)
+	}
+}
+
+func TestClientEnabled(t *testing.T) {
+	c, err := NewClientWithOptions(WithSender(NewRecordingSender()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Enabled() {
+		t.Fatal("client not enabled")
+	}
+	c.Close()
+	if c.Enabled() {
+		t.Fatal("closed client enabled")
+	}
+	if (&NoopClient{}).Enabled() {
+		t.Fatal("NoopClient enabled")
+	}
+}
+
+func TestClientWithPrefix(t *testing.T) {
+	rs := NewRecordingSender()
+	s, err := NewClientWithOptions(WithSender(rs), WithPrefix("parent"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := s.(*Client)
+	defer c.Close()
+
+	tenant := c.WithPrefix("tenant")
+	tenant.Inc("count", 1, 1.0)
+	tenant.SetPrefix("other")
+	tenant.Inc("count", 1, 1.0)
+	c.Inc("count", 1, 1.0)
+
+	expected := []string{"tenant.count:1|c", "other.count:1|c", "parent.count:1|c"}
+	sent := rs.GetSent()
+	if len(sent) != len(expected) {
+		t.Fatalf("got '%s' expected %q", sent, expected)
+	}
+	for i, e := range expected {
+		if string(sent[i]) != e {
+			t.Fatalf("got '%s' expected '%s'", sent[i], e)
+		}
+	}
+
+	if n := tenant.Stats().Sent; n != 2 {
+		t.Fatalf("got %d sent by the copy expected 2", n)
+	}
+	if n := c.Stats().Sent; n != 1 {
+		t.Fatalf("got %d sent by the original expected 1", n)
+	}
+
+	// closing the copy leaves the shared sender open
+	tenant.Close()
+	if err := c.Inc("count", 1, 1.0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSimpleSenderResolveError(t *testing.T) {
+	_, err := NewSimpleSender("invalid:address:8125")
+	if !errors.Is(err, ErrResolveAddr) {
+		t.Fatalf("got error '%v' expected '%v'", err, ErrResolveAddr)
+	}
+	var addrErr *net.AddrError
+	if !errors.As(err, &addrErr) {
+		t.Fatalf("got error '%v' expected it to wrap a *net.AddrError", err)
+	}
+	if errors.Is(err, ErrListen) {
+		t.Fatalf("got error '%v' matching '%v'", err, ErrListen)
+	}
+}
+
+func TestSimpleSenderBound(t *testing.T) {
+	l, err := newUDPListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	s, err := NewSimpleSenderBound("127.0.0.1:0", l.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if _, err := s.Send([]byte("test.count:1|c")); err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 128)
+	n, from, err := l.ReadFrom(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data[:n]) != "test.count:1|c" {
+		t.Fatalf("got '%s' expected 'test.count:1|c'", data[:n])
+	}
+	if ip := from.(*net.UDPAddr).IP; !ip.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Fatalf("got source address %s expected 127.0.0.1", ip)
+	}
+
+	// an address in TEST-NET-1, which is not assigned to the host
+	_, err = NewSimpleSenderBound("192.0.2.1:0", l.LocalAddr().String())
+	if !errors.Is(err, ErrListen) {
+		t.Fatalf("got error '%v' expected '%v'", err, ErrListen)
+	}
+	_, err = NewSimpleSenderBound("invalid:address:0", l.LocalAddr().String())
+	if !errors.Is(err, ErrResolveAddr) {
+		t.Fatalf("got error '%v' expected '%v'", err, ErrResolveAddr)
+	}
+}
+
+func TestSenderFromConn(t *testing.T) {
+	l, err := newUDPListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	c, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	s, err := NewSenderFromConn(c, l.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Send([]byte("test.count:1|c")); err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 128)
+	n, from, err := l.ReadFrom(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data[:n]) != "test.count:1|c" {
+		t.Fatalf("got '%s' expected 'test.count:1|c'", data[:n])
+	}
+	if from.String() != c.LocalAddr().String() {
+		t.Fatalf("got source address %s expected %s", from, c.LocalAddr())
+	}
+
+	// the borrowed connection is left open
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.WriteTo([]byte("test.count:1|c"), l.LocalAddr()); err != nil {
+		t.Fatalf("got error '%v' writing to the borrowed connection after Close", err)
+	}
+
+	o, err := NewSenderOwningConn(c, l.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.WriteTo([]byte("test.count:1|c"), l.LocalAddr()); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("got error '%v' expected the owned connection closed", err)
+	}
+}
+
+func TestSimpleSenderPacketTooLarge(t *testing.T) {
+	l, err := newUDPListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	s, err := NewSimpleSender(l.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// larger than any IPv4 UDP payload
+	data := bytes.Repeat([]byte("a"), 70000)
+	_, err = s.Send(data)
+	if !errors.Is(err, ErrPacketTooLarge) {
+		t.Fatalf("got error '%v' expected '%v'", err, ErrPacketTooLarge)
+	}
+	var pe *PacketTooLargeError
+	if !errors.As(err, &pe) || pe.Size != 70000 || pe.Limit != 65507 {
+		t.Fatalf("got %+v expected a size of 70000 and limit of 65507", pe)
+	}
+}
+
+func TestRetryBufferFull(t *testing.T) {
+	var tests = []struct {
+		Errs     []error
+		Calls    int
+		Expected error
+	}{
+		{[]error{nil}, 1, nil},
+		{[]error{syscall.ENOBUFS, nil}, 2, nil},
+		{[]error{syscall.EAGAIN, nil}, 2, nil},
+		{[]error{syscall.ENOBUFS, syscall.ENOBUFS}, 2, ErrSendBufferFull},
+		{[]error{syscall.ECONNREFUSED}, 1, syscall.ECONNREFUSED},
+	}
+
+	for _, tt := range tests {
+		calls := 0
+		_, err := retryBufferFull(func() (int, error) {
+			err := tt.Errs[calls]
+			calls++
+			return 0, err
+		})
+		if calls != tt.Calls {
+			t.Fatalf("errors %v got %d writes expected %d", tt.Errs, calls, tt.Calls)
+		}
+		if !errors.Is(err, tt.Expected) {
+			t.Fatalf("errors %v got error '%v' expected '%v'", tt.Errs, err, tt.Expected)
+		}
+	}
+}
+
+func TestClientIncMany(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithPrefix("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	err = c.IncMany(map[string]int64{"a": 1, "b": 2, "c": 3}, 1.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := rs.GetSent()
+	if len(sent) != 1 {
+		t.Fatalf("got %d packets expected 1", len(sent))
+	}
+	lines := strings.Split(string(sent[0]), "\n")
+	sort.Strings(lines)
+	expected := "test.a:1|c test.b:2|c test.c:3|c"
+	if strings.Join(lines, " ") != expected {
+		t.Fatalf("got '%s' expected '%s'", lines, expected)
+	}
+
+	err = c.IncMany(map[string]int64{"ok": 1, "": 1}, 1.0)
+	if err == nil {
+		t.Fatal("expected an error for an empty stat name")
+	}
+	if sent := rs.GetSent(); len(sent) != 2 || string(sent[1]) != "test.ok:1|c" {
+		t.Fatalf("got '%s' expected the valid counter sent", sent)
+	}
+}
+
+func TestClientIncManySplit(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithMaxPacketSize(20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// each "statN:1|c" is 9 bytes, so two fit in a packet
+	counts := make(map[string]int64)
+	for i := 0; i < 5; i++ {
+		counts["stat"+strconv.Itoa(i)] = 1
+	}
+	if err := c.IncMany(counts, 1.0); err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []string
+	sent := rs.GetSent()
+	for _, p := range sent {
+		if len(p) > 20 {
+			t.Fatalf("got a packet of %d bytes expected at most 20", len(p))
+		}
+		lines = append(lines, strings.Split(string(p), "\n")...)
+	}
+	if len(sent) != 3 || len(lines) != 5 {
+		t.Fatalf("got %d packets of %d metrics expected 3 of 5", len(sent), len(lines))
+	}
+	if stats := c.Stats(); stats.Sent != 5 {
+		t.Fatalf("got %d sent expected 5", stats.Sent)
+	}
+}
+
+func TestRateOneIn(t *testing.T) {
+	for n, expected := range map[int]float32{-1: 1, 0: 1, 1: 1, 4: 0.25, 10: 0.1} {
+		if rate := RateOneIn(n); rate != expected {
+			t.Fatalf("RateOneIn(%d) got %v expected %v", n, rate, expected)
+		}
+	}
+}
+
+func TestClientIncEvery(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithPrefix("test"), WithRandSource(constSource(0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.(*Client).IncEvery("count", 1, 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.(*Client).TimingEvery("timing", 2*time.Millisecond, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.(*Client).IncEvery("count", 1, 0); err == nil {
+		t.Fatal("expected an error for one in 0")
+	}
+
+	expected := []string{"test.count:1|c|@0.25", "test.timing:2.00|ms"}
+	sent := rs.GetSent()
+	if len(sent) != len(expected) {
+		t.Fatalf("got '%s' expected '%s'", sent, expected)
+	}
+	for i, e := range expected {
+		if string(sent[i]) != e {
+			t.Fatalf("got '%s' expected '%s'", sent[i], e)
+		}
+	}
+}
+
+func TestClientShutdown(t *testing.T) {
+	for stat, expected := range map[string]string{"": "test.shutdown:1|c", "app.stopping": "test.app.stopping:1|c"} {
+		rs := NewRecordingSender()
+		opts := []Option{WithSender(rs), WithPrefix("test")}
+		if stat != "" {
+			opts = append(opts, WithShutdownStat(stat))
+		}
+		c, err := NewClientWithOptions(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := c.(*Client).Shutdown(); err != nil {
+			t.Fatal(err)
+		}
+		sent := rs.GetSent()
+		if len(sent) != 1 || string(sent[0]) != expected {
+			t.Fatalf("got '%s' expected '%s'", sent, expected)
+		}
+		if err := c.Inc("count", 1, 1.0); !errors.Is(err, ErrClosed) {
+			t.Fatalf("got error '%v' expected '%v' after Shutdown", err, ErrClosed)
+		}
+		if err := c.(*Client).Shutdown(); !errors.Is(err, ErrClosed) {
+			t.Fatalf("second Shutdown got error '%v' expected '%v'", err, ErrClosed)
+		}
+	}
+}
+
+func TestClientInvalidFloat(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	b := c.(*Client).NewBatch()
+	scope := c.(*Client).BeginBatch()
+	sg, err := NewSmoothedGauge(c, "smoothed", 0.5, 1.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	methods := map[string]func(float64) error{
+		"GaugeFloat":            func(v float64) error { return c.GaugeFloat("stat", v, 1.0) },
+		"GaugeDeltaFloat":       func(v float64) error { return c.GaugeDeltaFloat("stat", v, 1.0) },
+		"HistogramFloat":        func(v float64) error { return c.HistogramFloat("stat", v, 1.0) },
+		"Distribution":          func(v float64) error { return c.Distribution("stat", v, 1.0) },
+		"Batch.GaugeFloat":      func(v float64) error { return b.GaugeFloat("stat", v, 1.0) },
+		"Batch.GaugeDeltaFloat": func(v float64) error { return b.GaugeDeltaFloat("stat", v, 1.0) },
+		"Batch.HistogramFloat":  func(v float64) error { return b.HistogramFloat("stat", v, 1.0) },
+		"Batch.Distribution":    func(v float64) error { return b.Distribution("stat", v, 1.0) },
+		"BatchScope.GaugeFloat": func(v float64) error { return scope.GaugeFloat("stat", v, 1.0) },
+		"SmoothedGauge.Update":  sg.Update,
+	}
+
+	for name, f := range methods {
+		for _, v := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+			if err := f(v); !errors.Is(err, ErrInvalidValue) {
+				t.Errorf("%s(%v) got %v expected ErrInvalidValue", name, v, err)
+			}
+		}
+	}
+	if err := scope.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if b.Len() != 0 {
+		t.Fatalf("got %d batched metrics expected none", b.Len())
+	}
+	if sent := rs.GetSent(); len(sent) != 0 {
+		t.Fatalf("got '%s' expected nothing sent", sent)
+	}
+
+	sg.Update(4)
+	if v := sg.Value(); v != 4 {
+		t.Fatalf("got %v expected invalid values to leave the average unchanged", v)
+	}
+}
+
+func TestClientRateGauge(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithPrefix("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.RateGauge("rate", 100, 2*time.Second, 1.0); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.RateGauge("rate", 3, 2*time.Second, 1.0); err != nil {
+		t.Fatal(err)
+	}
+	b := c.(*Client).NewBatch()
+	if err := b.RateGauge("rate", 5, 500*time.Millisecond, 1.0); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Submit(); err != nil {
+		t.Fatal(err)
+	}
+	for _, window := range []time.Duration{0, -time.Second} {
+		if err := c.RateGauge("rate", 1, window, 1.0); !errors.Is(err, ErrInvalidValue) {
+			t.Fatalf("window %v got %v expected ErrInvalidValue", window, err)
+		}
+	}
+
+	expected := []string{"test.rate:50|g", "test.rate:1.5|g", "test.rate:10|g"}
+	sent := rs.GetSent()
+	if len(sent) != len(expected) {
+		t.Fatalf("got '%s' expected '%s'", sent, expected)
+	}
+	for i, e := range expected {
+		if string(sent[i]) != e {
+			t.Fatalf("got '%s' expected '%s'", sent[i], e)
+		}
+	}
+}
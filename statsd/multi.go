@@ -0,0 +1,63 @@
+package statsd
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// MultiSender sends data to several child senders.
+type MultiSender struct {
+	senders []Sender
+	closed  int32
+	once    sync.Once
+}
+
+// Send sends the data to every child sender. A failure on one sender does
+// not prevent sending on the others; any errors are joined together.
+func (s *MultiSender) Send(data []byte) (int, error) {
+	if atomic.LoadInt32(&s.closed) != 0 {
+		return 0, ErrClosed
+	}
+	var errs []error
+	for _, sender := range s.senders {
+		if _, err := sender.Send(data); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return len(data), errors.Join(errs...)
+}
+
+// Flush flushes every child sender that buffers data, joining any errors
+// together.
+func (s *MultiSender) Flush() error {
+	var errs []error
+	for _, sender := range s.senders {
+		if f, ok := sender.(flusher); ok {
+			if err := f.Flush(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Closes every child sender, joining any errors together. Later calls
+// return nil, and Send returns ErrClosed once closed.
+func (s *MultiSender) Close() error {
+	var errs []error
+	s.once.Do(func() {
+		atomic.StoreInt32(&s.closed, 1)
+		for _, sender := range s.senders {
+			if err := sender.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// Returns a new MultiSender, that fans out to each of senders.
+func NewMultiSender(senders ...Sender) Sender {
+	return &MultiSender{senders: senders}
+}
@@ -0,0 +1,79 @@
+package statsd
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// errorSender is a Sender that always fails.
+type errorSender struct {
+	err error
+}
+
+func (s errorSender) Send(data []byte) (int, error) { return 0, s.err }
+func (s errorSender) Close() error                  { return s.err }
+
+func TestMultiSender(t *testing.T) {
+	r1 := NewRecordingSender()
+	r2 := NewRecordingSender()
+	s := NewMultiSender(r1, r2)
+
+	_, err := s.Send([]byte("test.count:1|c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range []*RecordingSender{r1, r2} {
+		sent := r.GetSent()
+		if len(sent) != 1 || string(sent[0]) != "test.count:1|c" {
+			t.Fatalf("got '%s' expected 'test.count:1|c'", sent)
+		}
+	}
+
+	err = s.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMultiSenderErrors(t *testing.T) {
+	e1 := errors.New("first")
+	e2 := errors.New("second")
+	r := NewRecordingSender()
+	s := NewMultiSender(errorSender{e1}, r, errorSender{e2})
+
+	_, err := s.Send([]byte("test.count:1|c"))
+	if !errors.Is(err, e1) || !errors.Is(err, e2) {
+		t.Fatalf("got error '%v' expected both child errors", err)
+	}
+	if len(r.GetSent()) != 1 {
+		t.Fatal("a failing sender prevented sending on the others")
+	}
+
+	err = s.Close()
+	if !errors.Is(err, e1) || !errors.Is(err, e2) {
+		t.Fatalf("got error '%v' expected both child errors", err)
+	}
+}
+
+func TestMultiSenderFlush(t *testing.T) {
+	r1 := NewRecordingSender()
+	r2 := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(NewMultiSender(newBufferedSender(r1, time.Hour, 1432, 0), r2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.Inc("count", 1, 1.0)
+	if n := len(r1.GetSent()); n != 0 {
+		t.Fatalf("got %d sent expected the buffered child to hold the metric", n)
+	}
+	if err := c.(*Client).Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if sent := r1.GetSent(); len(sent) != 1 || string(sent[0]) != "count:1|c\n" {
+		t.Fatalf("got '%s' expected the buffered child flushed", sent)
+	}
+}
@@ -0,0 +1,132 @@
+package statsd
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// NameMode controls how a Client handles stat names containing characters
+// reserved by the statsd wire protocol (":", "|" and "@").
+type NameMode int
+
+const (
+	// NamePermissive sends stat names as is. This is the default.
+	NamePermissive NameMode = iota
+	// NameStrict returns an error for stat names with reserved characters.
+	NameStrict
+	// NameLenient replaces reserved characters in stat names with "_".
+	NameLenient
+)
+
+// reservedChars are the characters that break the statsd wire protocol when
+// used in a stat name.
+const reservedChars = ":|@"
+
+var reservedReplacer = strings.NewReplacer(":", "_", "|", "_", "@", "_")
+
+// checkStat validates stat according to mode, returning the name to send.
+// Empty names are always an error.
+func checkStat(stat string, mode NameMode) (string, error) {
+	if stat == "" {
+		return "", fmt.Errorf("Empty stat name")
+	}
+	if mode == NamePermissive || !strings.ContainsAny(stat, reservedChars) {
+		return stat, nil
+	}
+	if mode == NameStrict {
+		return "", fmt.Errorf("Invalid stat name %q, contains one of %q", stat, reservedChars)
+	}
+	return reservedReplacer.Replace(stat), nil
+}
+
+// SanitizeName replaces the characters ":", "|", "@", "/" and whitespace in
+// name with "_". It is the default sanitizer for WithNameSanitizer, and
+// returns names without those characters unchanged, without allocating.
+func SanitizeName(name string) string {
+	if strings.IndexFunc(name, isIllegalNameRune) == -1 {
+		return name
+	}
+	return strings.Map(func(r rune) rune {
+		if isIllegalNameRune(r) {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+func isIllegalNameRune(r rune) bool {
+	switch r {
+	case ':', '|', '@', '/':
+		return true
+	}
+	return unicode.IsSpace(r)
+}
+
+// nameCacheSize is the most stat names a nameCache holds.
+const nameCacheSize = 1024
+
+// nameCache holds the names sent for stat names changed by the sanitizer or
+// NameLenient, so that repeated names are not sanitized again, which
+// allocates. Only the stat name is cached: the prefix is appended to it in a
+// pooled buffer for each metric, which does not allocate. Once full, caching
+// a name evicts an arbitrary other one, so that names of high cardinality do
+// not grow it without bound.
+type nameCache struct {
+	mx    sync.RWMutex
+	names map[string]string
+}
+
+func newNameCache() *nameCache {
+	return &nameCache{names: make(map[string]string)}
+}
+
+func (c *nameCache) get(stat string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	c.mx.RLock()
+	name, ok := c.names[stat]
+	c.mx.RUnlock()
+	return name, ok
+}
+
+// put caches name for stat, copying both, as they may share the memory of a
+// byte slice passed to RawBytes.
+func (c *nameCache) put(stat, name string) {
+	if c == nil {
+		return
+	}
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if len(c.names) >= nameCacheSize {
+		// map iteration starts at a random entry
+		for k := range c.names {
+			delete(c.names, k)
+			break
+		}
+	}
+	c.names[strings.Clone(stat)] = strings.Clone(name)
+}
+
+// name returns the name to send for stat, after the sanitizer and the
+// NameMode of the client.
+func (s *Client) name(stat string) (string, error) {
+	if s.sanitizer == nil && s.nameMode != NameLenient {
+		return checkStat(stat, s.nameMode)
+	}
+	if name, ok := s.names.get(stat); ok {
+		return name, nil
+	}
+
+	name := stat
+	if s.sanitizer != nil {
+		name = s.sanitizer(name)
+	}
+	name, err := checkStat(name, s.nameMode)
+	if err == nil && name != stat {
+		s.names.put(stat, name)
+	}
+	return name, err
+}
@@ -0,0 +1,172 @@
+package statsd
+
+import (
+	"strconv"
+	"testing"
+)
+
+var checkStatTests = []struct {
+	Stat     string
+	Mode     NameMode
+	Expected string
+	Valid    bool
+}{
+	{"", NamePermissive, "", false},
+	{"", NameStrict, "", false},
+	{"", NameLenient, "", false},
+	{"a:b", NamePermissive, "a:b", true},
+	{"a.b", NameStrict, "a.b", true},
+	{"a:b", NameStrict, "", false},
+	{"a|b", NameStrict, "", false},
+	{"a@b", NameStrict, "", false},
+	{"a.b", NameLenient, "a.b", true},
+	{"a:b", NameLenient, "a_b", true},
+	{"a|b", NameLenient, "a_b", true},
+	{"a@b", NameLenient, "a_b", true},
+}
+
+func TestCheckStat(t *testing.T) {
+	for _, tt := range checkStatTests {
+		stat, err := checkStat(tt.Stat, tt.Mode)
+		if (err == nil) != tt.Valid {
+			t.Fatalf("checkStat(%q, %v) got err '%v' expected valid %v", tt.Stat, tt.Mode, err, tt.Valid)
+		}
+		if stat != tt.Expected {
+			t.Fatalf("checkStat(%q, %v) got %q expected %q", tt.Stat, tt.Mode, stat, tt.Expected)
+		}
+	}
+}
+
+func TestClientEmptyStat(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Inc("", 1, 1.0)
+	if err == nil {
+		t.Fatal("expected an error for an empty stat name")
+	}
+	if len(rs.GetSent()) != 0 {
+		t.Fatal("metric with an empty name was sent")
+	}
+}
+
+func TestClientNameModeLenient(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithPrefix("test"), WithNameMode(NameLenient))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Inc("a:b|c@d", 1, 1.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "test.a_b_c_d:1|c"
+	sent := rs.GetSent()
+	if len(sent) != 1 || string(sent[0]) != expected {
+		t.Fatalf("got '%s' expected '%s'", sent, expected)
+	}
+}
+
+var sanitizeNameTests = []struct {
+	Name     string
+	Expected string
+}{
+	{"api.v1.get", "api.v1.get"},
+	{"api/v1:get status", "api_v1_get_status"},
+	{"a|b@c\td", "a_b_c_d"},
+}
+
+func TestSanitizeName(t *testing.T) {
+	for _, tt := range sanitizeNameTests {
+		if name := SanitizeName(tt.Name); name != tt.Expected {
+			t.Fatalf("SanitizeName(%q) got %q expected %q", tt.Name, name, tt.Expected)
+		}
+	}
+}
+
+func TestClientNameSanitizer(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithPrefix("test"), WithNameSanitizer(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Inc("api/v1:get status", 1, 1.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "test.api_v1_get_status:1|c"
+	sent := rs.GetSent()
+	if len(sent) != 1 || string(sent[0]) != expected {
+		t.Fatalf("got '%s' expected '%s'", sent, expected)
+	}
+}
+
+func TestClientNameCache(t *testing.T) {
+	rs := NewRecordingSender()
+	calls := 0
+	sanitize := func(stat string) string {
+		calls++
+		return SanitizeName(stat)
+	}
+	c, err := NewClientWithOptions(WithSender(rs), WithPrefix("test"), WithNameSanitizer(sanitize))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stat := []byte("api/users")
+	for i := 0; i < 3; i++ {
+		err = c.RawBytes(stat, []byte("1|c"), 1.0)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the cached name must not share the memory of the stat passed in
+	copy(stat, "xxxxxxxxx")
+	err = c.Raw("api/users", "1|c", 1.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != 1 {
+		t.Fatalf("sanitizer called %d times expected 1", calls)
+	}
+	sent := rs.GetSent()
+	if len(sent) != 4 {
+		t.Fatalf("got %d payloads expected 4", len(sent))
+	}
+	for _, s := range sent {
+		if string(s) != "test.api_users:1|c" {
+			t.Fatalf("got '%s' expected 'test.api_users:1|c'", s)
+		}
+	}
+}
+
+func TestNameCacheBounded(t *testing.T) {
+	nc := newNameCache()
+	for i := 0; i < nameCacheSize+10; i++ {
+		nc.put(strconv.Itoa(i), "name")
+	}
+	if len(nc.names) != nameCacheSize {
+		t.Fatalf("got %d cached names expected %d", len(nc.names), nameCacheSize)
+	}
+	// names past the cache size evict others
+	if _, ok := nc.get(strconv.Itoa(nameCacheSize + 9)); !ok {
+		t.Fatal("latest name not cached once full")
+	}
+}
+
+func BenchmarkClientRawSanitized(b *testing.B) {
+	c, _ := NewClientWithOptions(WithSender(discardSender{}), WithPrefix("test"), WithNameSanitizer(nil))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Raw("api/users", "1|c", 1.0)
+	}
+}
@@ -1,7 +1,13 @@
 package statsd
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
+// NoopClient is a Statter whose methods do nothing and always return nil.
+// It never opens or writes to a socket, so it can be held in place of a real
+// client when stats are disabled.
 type NoopClient struct {
 	// prefix for statsd name
 	prefix string
@@ -12,6 +18,29 @@ func (s *NoopClient) Close() error {
 	return nil
 }
 
+// Flush does nothing.
+func (s *NoopClient) Flush() error {
+	return nil
+}
+
+// Enabled returns false, as nothing is ever sent.
+func (s *NoopClient) Enabled() bool {
+	return false
+}
+
+// Stats returns zero counts, as nothing is ever sent.
+func (s *NoopClient) Stats() ClientStats {
+	return ClientStats{}
+}
+
+// PublishExpvar publishes zero Stats as an expvar with the given name, as
+// for Client.PublishExpvar.
+func (s *NoopClient) PublishExpvar(name string) {
+	publishExpvar(name, func() interface{} {
+		return s.Stats()
+	})
+}
+
 // Increments a statsd count type.
 // stat is a string name for the metric.
 // value is the integer value
@@ -28,6 +57,13 @@ func (s *NoopClient) Dec(stat string, value int64, rate float32) error {
 	return nil
 }
 
+// Increments many statsd count types.
+// counts maps the string names of the metrics to their integer values.
+// rate is the sample rate (0.0 to 1.0).
+func (s *NoopClient) IncMany(counts map[string]int64, rate float32) error {
+	return nil
+}
+
 // Submits/Updates a statsd gauge type.
 // stat is a string name for the metric.
 // value is the integer value.
@@ -44,6 +80,57 @@ func (s *NoopClient) GaugeDelta(stat string, value int64, rate float32) error {
 	return nil
 }
 
+// Submits/Updates a statsd gauge type with a floating point value.
+// stat is a string name for the metric.
+// value is the float value.
+// rate is the sample rate (0.0 to 1.0).
+func (s *NoopClient) GaugeFloat(stat string, value float64, rate float32) error {
+	return nil
+}
+
+// Submits/Updates a statsd gauge type with an unsigned value.
+// stat is a string name for the metric.
+// value is the unsigned integer value.
+// rate is the sample rate (0.0 to 1.0).
+func (s *NoopClient) GaugeUint64(stat string, value uint64, rate float32) error {
+	return nil
+}
+
+// Submits/Updates a statsd gauge type with a time, as seconds since the Unix
+// epoch.
+// stat is a string name for the metric.
+// t is the time.
+// rate is the sample rate (0.0 to 1.0).
+func (s *NoopClient) GaugeTime(stat string, t time.Time, rate float32) error {
+	return nil
+}
+
+// Submits/Updates a statsd gauge type with a time, as milliseconds since the
+// Unix epoch.
+// stat is a string name for the metric.
+// t is the time.
+// rate is the sample rate (0.0 to 1.0).
+func (s *NoopClient) GaugeTimeMillis(stat string, t time.Time, rate float32) error {
+	return nil
+}
+
+// Submits/Updates a statsd gauge type with the rate per second of count
+// events over window.
+// stat is a string name for the metric.
+// count is the number of events, and window the time they happened over.
+// rate is the sample rate (0.0 to 1.0).
+func (s *NoopClient) RateGauge(stat string, count int64, window time.Duration, rate float32) error {
+	return nil
+}
+
+// Submits a floating point delta to a statsd gauge.
+// stat is the string name for the metric.
+// value is the (positive or negative) change.
+// rate is the sample rate (0.0 to 1.0).
+func (s *NoopClient) GaugeDeltaFloat(stat string, value float64, rate float32) error {
+	return nil
+}
+
 // Submits a statsd timing type.
 // stat is a string name for the metric.
 // delta is the time duration value in milliseconds
@@ -60,6 +147,115 @@ func (s *NoopClient) TimingDuration(stat string, delta time.Duration, rate float
 	return nil
 }
 
+// Submits/Updates a statsd gauge type with a duration in milliseconds.
+// stat is a string name for the metric.
+// delta is the timing value as time.Duration
+// rate is the sample rate (0.0 to 1.0).
+func (s *NoopClient) TimingGauge(stat string, delta time.Duration, rate float32) error {
+	return nil
+}
+
+// Submits many statsd timings for one stat in a single line.
+// stat is a string name for the metric.
+// deltas are the timing values as time.Duration
+// rate is the sample rate (0.0 to 1.0).
+func (s *NoopClient) TimingMulti(stat string, deltas []time.Duration, rate float32) error {
+	return nil
+}
+
+// Submits a stats set type.
+// stat is a string name for the metric.
+// value is the string value
+// rate is the sample rate (0.0 to 1.0).
+func (s *NoopClient) Set(stat string, value string, rate float32) error {
+	return nil
+}
+
+// Submits a statsd histogram type.
+// stat is a string name for the metric.
+// value is the integer value.
+// rate is the sample rate (0.0 to 1.0).
+func (s *NoopClient) Histogram(stat string, value int64, rate float32) error {
+	return nil
+}
+
+// Submits a statsd histogram type with a floating point value.
+// stat is a string name for the metric.
+// value is the float value.
+// rate is the sample rate (0.0 to 1.0).
+func (s *NoopClient) HistogramFloat(stat string, value float64, rate float32) error {
+	return nil
+}
+
+// Submits a DogStatsD distribution type.
+// stat is a string name for the metric.
+// value is the float value.
+// rate is the sample rate (0.0 to 1.0).
+func (s *NoopClient) Distribution(stat string, value float64, rate float32) error {
+	return nil
+}
+
+// Returns a Batch that does nothing when submitted.
+func (s *NoopClient) NewBatch() *Batch {
+	return &Batch{}
+}
+
+// Submits a sampled statsd timing type.
+// stat is a string name for the metric.
+// delta is the timing value as time.Duration
+// rate is the sample rate (0.0 to 1.0).
+func (s *NoopClient) SampledTiming(stat string, delta time.Duration, rate float32) error {
+	return nil
+}
+
+// Counter returns a Counter that does nothing.
+func (s *NoopClient) Counter(stat string, rate float32) Counter {
+	return NewCounter(s, stat, rate)
+}
+
+// BoundGauge returns a Gauge that does nothing.
+func (s *NoopClient) BoundGauge(stat string, rate float32) Gauge {
+	return NewGauge(s, stat, rate)
+}
+
+// Timer returns a Timer that does nothing.
+func (s *NoopClient) Timer(stat string, rate float32) Timer {
+	return NewTimer(s, stat, rate)
+}
+
+// Submits a meter type.
+// stat is a string name for the metric.
+// value is the integer value
+// rate is the sample rate (0.0 to 1.0)
+func (s *NoopClient) Meter(stat string, value int64, rate float32) error {
+	return nil
+}
+
+// Submits a metric of a type without a method of its own.
+// stat is a string name for the metric.
+// value is the preformatted value string.
+// suffix is the metric type.
+// rate is the sample rate (0.0 to 1.0).
+func (s *NoopClient) Custom(stat string, value string, suffix string, rate float32) error {
+	return nil
+}
+
+// Returns a Timing started now, which does nothing when sent.
+func (s *NoopClient) NewTiming() Timing {
+	return newTiming(s, realClock{})
+}
+
+// Calls f, and does nothing else.
+func (s *NoopClient) Time(stat string, rate float32, f func()) error {
+	f()
+	return nil
+}
+
+// Returns a function that does nothing, leaving any panic to continue.
+func (s *NoopClient) Recover(stat string) func() {
+	return func() {}
+}
+
 // Raw formats the statsd event data, handles sampling, prepares it,
 // and sends it to the server.
 // stat is the string name for the metric.
@@ -69,11 +265,56 @@ func (s *NoopClient) Raw(stat string, value string, rate float32) error {
 	return nil
 }
 
+// Submits a stat from raw byte slices.
+// stat is the name for the metric.
+// value is the preformatted "raw" value.
+// rate is the sample rate (0.0 to 1.0).
+func (s *NoopClient) RawBytes(stat []byte, value []byte, rate float32) error {
+	return nil
+}
+
+// Submits a DogStatsD service check.
+// name is the name of the check.
+// status is the status of the check, such as ServiceCheckOK.
+func (s *NoopClient) ServiceCheck(name string, status int, tags ...Tag) error {
+	return nil
+}
+
+// Submits a DogStatsD event.
+// title is the title of the event, and text its body.
+func (s *NoopClient) Event(title, text string, tags ...Tag) error {
+	return nil
+}
+
 // Sets/Updates the statsd client prefix
 func (s *NoopClient) SetPrefix(prefix string) {
+	if s == nil {
+		return
+	}
 	s.prefix = prefix
 }
 
+// Returns a new NoopClient with prefix appended to this client's prefix.
+func (s *NoopClient) NewSubStatter(prefix string) Statter {
+	if s == nil || s.prefix == "" {
+		return &NoopClient{prefix: prefix}
+	}
+	if prefix == "" {
+		return &NoopClient{prefix: s.prefix}
+	}
+	return &NoopClient{prefix: s.prefix + "." + prefix}
+}
+
+// Returns the NoopClient itself, as nothing is ever sent.
+func (s *NoopClient) WithContext(ctx context.Context) Statter {
+	return s
+}
+
+// Returns the NoopClient itself, as tags are never sent.
+func (s *NoopClient) WithTags(tags ...Tag) Statter {
+	return s
+}
+
 // Returns a pointer to a new NoopClient, and an error (always nil, just
 // supplied to support api convention).
 // Use variadic arguments to support identical format as NewClient, or a more
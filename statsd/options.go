@@ -0,0 +1,480 @@
+package statsd
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// clientConfig holds the settings gathered from Options by
+// NewClientWithOptions.
+type clientConfig struct {
+	addr        string
+	prefix      string
+	separator   *string
+	suffix      string
+	nameMode    NameMode
+	sanitizer   func(string) string
+	sender      Sender
+	defaultRate float32
+	typeRates   map[string]float32
+	randSource  rand.Source
+	sampler     Sampler
+	onError     func(err error)
+	logger      func(payload []byte)
+	timeout     time.Duration
+	clock       Clock
+	round       bool
+	zeroTimings ZeroTimingPolicy
+	precision   *int
+	tags        []Tag
+	tagFormat   TagFormat
+	rateLimit   int
+	emitEvery   time.Duration
+	maxPacket   int
+	newline     bool
+	noRate      bool
+	dryRun      bool
+	shutdown    *string
+	guarantee   *time.Duration
+	coalesce    *time.Duration
+	repeat      time.Duration
+}
+
+// Option configures a Client created with NewClientWithOptions.
+type Option func(*clientConfig)
+
+// WithAddr sets the "hostname:port" address to send to, using a
+// SimpleSender. It may not be combined with WithSender.
+func WithAddr(addr string) Option {
+	return func(c *clientConfig) {
+		c.addr = addr
+	}
+}
+
+// WithWriteTimeout sets a deadline for each write made by the sender created
+// by WithAddr. Writes that time out are returned as errors, and counted in
+// the Errors of Stats. It may not be combined with WithSender.
+func WithWriteTimeout(timeout time.Duration) Option {
+	return func(c *clientConfig) {
+		c.timeout = timeout
+	}
+}
+
+// WithPrefix sets the statsd client prefix.
+func WithPrefix(prefix string) Option {
+	return func(c *clientConfig) {
+		c.prefix = prefix
+	}
+}
+
+// WithPrefixSeparator sets the separator placed between the prefix and stat
+// names, which defaults to ".". The separator is not repeated when the prefix
+// already ends with it.
+func WithPrefixSeparator(sep string) Option {
+	return func(c *clientConfig) {
+		c.separator = &sep
+	}
+}
+
+// WithSuffix sets a suffix appended to every stat name, after the prefix
+// separator, such as "us_east" for "prefix.stat.us_east". The separator is
+// not repeated when the suffix already starts with it. It is kept by sub
+// statters and derived clients.
+func WithSuffix(suffix string) Option {
+	return func(c *clientConfig) {
+		c.suffix = suffix
+	}
+}
+
+// WithNameMode sets how stat names containing reserved characters are
+// handled. The default is NamePermissive.
+func WithNameMode(mode NameMode) Option {
+	return func(c *clientConfig) {
+		c.nameMode = mode
+	}
+}
+
+// WithNameSanitizer sets a function applied to every stat name, before the
+// prefix is added. If f is nil, SanitizeName is used. The names it returns are
+// cached, so f must always return the same name for the same stat.
+func WithNameSanitizer(f func(string) string) Option {
+	return func(c *clientConfig) {
+		if f == nil {
+			f = SanitizeName
+		}
+		c.sanitizer = f
+	}
+}
+
+// WithTags sets DogStatsD tags appended to every metric sent by the client,
+// such as the service and environment. Tags added later with the WithTags
+// method of the client are merged with these.
+func WithTags(tags ...Tag) Option {
+	return func(c *clientConfig) {
+		c.tags = mergeTags(c.tags, tags)
+	}
+}
+
+// WithTagFormat sets how tags are serialized, for servers that expect a
+// format other than the default TagFormatDatadog. It applies to tags set by
+// both the WithTags option and method, including on Raw stats.
+func WithTagFormat(format TagFormat) Option {
+	return func(c *clientConfig) {
+		c.tagFormat = format
+	}
+}
+
+// WithSender sets the Sender used to send metrics. It may not be combined
+// with WithAddr.
+func WithSender(sender Sender) Option {
+	return func(c *clientConfig) {
+		c.sender = sender
+	}
+}
+
+// WithDefaultRate sets the sample rate used for calls made with a rate of 0
+// or UseDefaultRate, for metric types without a rate set with
+// WithTypeDefaultRate. The rate must be greater than 0 and at most 1.
+func WithDefaultRate(rate float32) Option {
+	return func(c *clientConfig) {
+		c.defaultRate = rate
+	}
+}
+
+// WithTypeDefaultRate sets the sample rate used for metrics of metricType
+// when called with a rate of 0 or UseDefaultRate, overriding WithDefaultRate
+// for that type, such as to sample timers more heavily than counters.
+// metricType is the statsd type: "c", "g", "ms", "s", "h", "d" or "m".
+// The rate must be greater than 0 and at most 1.
+func WithTypeDefaultRate(metricType string, rate float32) Option {
+	return func(c *clientConfig) {
+		if c.typeRates == nil {
+			c.typeRates = make(map[string]float32)
+		}
+		c.typeRates[metricType] = rate
+	}
+}
+
+// WithRandSource sets the source of randomness used for sampling.
+func WithRandSource(src rand.Source) Option {
+	return func(c *clientConfig) {
+		c.randSource = src
+	}
+}
+
+// WithSampler sets the Sampler deciding which metrics with a rate below 1
+// are sent, such as a HashSampler. By default metrics are sampled randomly,
+// with the source set by WithRandSource, which is not used once a Sampler
+// is set.
+func WithSampler(sampler Sampler) Option {
+	return func(c *clientConfig) {
+		c.sampler = sampler
+	}
+}
+
+// WithOnError sets a function called with every error returned by the
+// sender. It is called without any client locks held, so it may use the
+// client.
+func WithOnError(f func(err error)) Option {
+	return func(c *clientConfig) {
+		c.onError = f
+	}
+}
+
+// WithLogger sets a function called with every payload just before it is
+// handed to the sender, as the bytes sent on the wire, such as to log what
+// the client sends while debugging. Unlike WithOnError it is called whether
+// or not the send succeeds. payload must not be modified or retained after
+// the function returns.
+func WithLogger(f func(payload []byte)) Option {
+	return func(c *clientConfig) {
+		c.logger = f
+	}
+}
+
+// WithClock sets the Clock used to measure durations for NewTiming and Time,
+// for example to control time in tests. The default uses the system time.
+func WithClock(clock Clock) Option {
+	return func(c *clientConfig) {
+		c.clock = clock
+	}
+}
+
+// WithRoundedTimings makes TimingDuration send durations rounded to whole
+// milliseconds, such as "2|ms", for servers that do not accept fractional
+// timings. By default two decimal places are sent, such as "1.50|ms".
+func WithRoundedTimings() Option {
+	return func(c *clientConfig) {
+		c.round = true
+	}
+}
+
+// WithTimingPrecision sets the number of decimal places of milliseconds sent
+// by TimingDuration, which defaults to 2, and 0 sends whole milliseconds. It
+// must be from 0 to 9, as 9 represents any duration exactly.
+// WithRoundedTimings takes precedence over this option.
+func WithTimingPrecision(decimals int) Option {
+	return func(c *clientConfig) {
+		c.precision = &decimals
+	}
+}
+
+// WithZeroTimings sets how timings sent as zero are handled, such as
+// "0|ms", which some servers treat as invalid. By default they are sent as
+// is, with ZeroTimingSend.
+func WithZeroTimings(policy ZeroTimingPolicy) Option {
+	return func(c *clientConfig) {
+		c.zeroTimings = policy
+	}
+}
+
+// WithRateLimit caps the number of sends per second, wrapping the sender in a
+// RateLimitedSender, as a global safety valve independent of sampling.
+// Metrics over the limit are dropped silently, and counted in the Dropped of
+// Stats; set WithOnError to be told about them.
+func WithRateLimit(perSecond int) Option {
+	return func(c *clientConfig) {
+		c.rateLimit = perSecond
+	}
+}
+
+// WithSampledTimingInterval sets the longest SampledTiming goes without
+// sending a stat, which defaults to 10 seconds.
+func WithSampledTimingInterval(interval time.Duration) Option {
+	return func(c *clientConfig) {
+		c.emitEvery = interval
+	}
+}
+
+// WithMaxPacketSize sets the largest payload in bytes the client hands to the
+// sender, including the prefix and tags, so that no datagram is sent that
+// would be dropped by the network. Larger metrics are not sent, and an error
+// matching ErrPacketTooLarge with errors.Is is returned and counted in the
+// Errors of Stats. It also sets the largest size of a Batch.
+func WithMaxPacketSize(size int) Option {
+	return func(c *clientConfig) {
+		c.maxPacket = size
+	}
+}
+
+// WithNewlineTerminator sets whether a newline is appended to each payload
+// before it is sent, for servers that read metrics by line even over UDP. It
+// is off by default, and not needed with TCPSender or WriterSender, which
+// terminate each payload themselves. The newline counts toward the size set
+// with WithMaxPacketSize.
+func WithNewlineTerminator(enabled bool) Option {
+	return func(c *clientConfig) {
+		c.newline = enabled
+	}
+}
+
+// WithSampleRateSuffix sets whether sampled metrics are sent with their
+// rate, as "|@0.5", which is the default. When false, metrics are still
+// sampled, but sent without the rate, for servers that reject it. As the
+// server then cannot scale the values it receives, the counts and timings
+// it reports are under-reported by the sample rate.
+func WithSampleRateSuffix(enabled bool) Option {
+	return func(c *clientConfig) {
+		c.noRate = !enabled
+	}
+}
+
+// WithDryRun sets whether the client only records what it would send,
+// without opening a socket, such as to check instrumentation in tests
+// without a statsd server. Metrics are still formatted and sampled as they
+// would be, and the payloads are returned by the Sent method of the Client.
+// An address set with WithAddr is not used, and it may not be combined with
+// WithSender.
+func WithDryRun(enabled bool) Option {
+	return func(c *clientConfig) {
+		c.dryRun = enabled
+	}
+}
+
+// WithShutdownStat sets the counter incremented by Client.Shutdown, which
+// defaults to "shutdown", under the prefix of the client.
+func WithShutdownStat(stat string) Option {
+	return func(c *clientConfig) {
+		c.shutdown = &stat
+	}
+}
+
+// WithSampleRateGuarantee makes sampled counters exact, so that counters
+// with little traffic are not lost to sampling. The values of counters
+// sampled out are added up by the client instead of being dropped, and sent
+// with the next value sampled in, or every interval, as a total without a
+// rate. Sampling then only reduces the number of packets. Counters in a
+// Batch are not affected.
+func WithSampleRateGuarantee(interval time.Duration) Option {
+	return func(c *clientConfig) {
+		c.guarantee = &interval
+	}
+}
+
+// WithRepeatCompression collapses metrics sent with the same stat, value and
+// type within window into one, such as a counter hit many times with the
+// same value, to send fewer packets. Counters are sent once with their sum,
+// and timings, histograms and distributions once with the sample rate
+// divided by the number of repeats, as "stat:12|ms|@0.25" for four repeats,
+// so that the server still counts each of them. Repeated sets are sent once.
+// Gauges are sent as is. At most 1024 distinct metrics are held per window;
+// others are sent as is.
+//
+// Metrics held are delayed by up to window, and sent on Flush and Close.
+// Errors sending them at the end of a window are not reported.
+func WithRepeatCompression(window time.Duration) Option {
+	return func(c *clientConfig) {
+		c.repeat = window
+	}
+}
+
+// WithGaugeDeltaCoalescing makes GaugeDelta and GaugeDeltaFloat add up the
+// deltas of each gauge in the client instead of sending each one, and send
+// the net change every interval, on Flush and on Close, without a rate.
+// Deltas are sampled before being added, and a net change of 0 is not sent.
+// Setting the gauge, such as with Gauge or GaugeFloat, first sends its
+// pending change, so that the server applies them in order. Gauges in a
+// Batch are not affected.
+func WithGaugeDeltaCoalescing(interval time.Duration) Option {
+	return func(c *clientConfig) {
+		c.coalesce = &interval
+	}
+}
+
+// Returns a new Client configured by opts, and an error.
+//
+// Exactly one of WithAddr or WithSender must be supplied.
+func NewClientWithOptions(opts ...Option) (Statter, error) {
+	cfg := &clientConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if cfg.defaultRate != 0 {
+		if err := validateRate(cfg.defaultRate); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.rateLimit < 0 {
+		return nil, errors.New("Rate limit must be greater than 0")
+	}
+
+	if cfg.repeat < 0 {
+		return nil, errors.New("Repeat compression window must be greater than 0")
+	}
+
+	if cfg.guarantee != nil && *cfg.guarantee <= 0 {
+		return nil, errors.New("Sample rate guarantee interval must be greater than 0")
+	}
+
+	if cfg.coalesce != nil && *cfg.coalesce <= 0 {
+		return nil, errors.New("Gauge delta coalescing interval must be greater than 0")
+	}
+
+	if cfg.precision != nil && (*cfg.precision < 0 || *cfg.precision > 9) {
+		return nil, errors.New("Timing precision must be from 0 to 9 decimal places")
+	}
+
+	if cfg.maxPacket < 0 {
+		return nil, errors.New("Max packet size must be greater than 0")
+	}
+
+	for _, rate := range cfg.typeRates {
+		if err := validateRate(rate); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.addr != "" && cfg.sender != nil {
+		return nil, errors.New("WithAddr and WithSender are mutually exclusive")
+	}
+
+	if cfg.timeout != 0 && cfg.sender != nil {
+		return nil, errors.New("WithWriteTimeout and WithSender are mutually exclusive")
+	}
+
+	if cfg.dryRun && cfg.sender != nil {
+		return nil, errors.New("WithDryRun and WithSender are mutually exclusive")
+	}
+
+	var recorder *RecordingSender
+	if cfg.dryRun {
+		recorder = NewRecordingSender()
+		cfg.sender = recorder
+	}
+
+	sender := cfg.sender
+	if sender == nil {
+		if cfg.addr == "" {
+			return nil, errors.New("One of WithAddr or WithSender is required")
+		}
+		var err error
+		sender, err = NewSimpleSenderWithTimeout(cfg.addr, cfg.timeout)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.rateLimit != 0 {
+		var err error
+		sender, err = NewRateLimitedSender(sender, cfg.rateLimit)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// before the rate limit, so that collapsed metrics count once
+	if cfg.repeat > 0 {
+		sender = newRepeatSender(sender, cfg.repeat)
+	}
+
+	client := newClient(sender, cfg.prefix)
+	client.defaultRate = cfg.defaultRate
+	client.typeRates = cfg.typeRates
+	client.onError = cfg.onError
+	client.logger = cfg.logger
+	client.maxPacketSize = cfg.maxPacket
+	client.newline = cfg.newline
+	client.noRateSuffix = cfg.noRate
+	client.recorder = recorder
+	if cfg.shutdown != nil {
+		client.shutdownStat = *cfg.shutdown
+	}
+	client.nameMode = cfg.nameMode
+	client.sanitizer = cfg.sanitizer
+	client.roundTimings = cfg.round
+	client.zeroTimings = cfg.zeroTimings
+	client.tagFormat = cfg.tagFormat
+	if len(cfg.tags) > 0 {
+		client.tags = cfg.tags
+		client.tagString = formatTags(cfg.tags, cfg.tagFormat)
+	}
+	if cfg.precision != nil {
+		client.timingPrecision = *cfg.precision
+	}
+	if cfg.emitEvery > 0 {
+		client.emitInterval = cfg.emitEvery
+	}
+	if cfg.clock != nil {
+		client.clock = cfg.clock
+	}
+	if cfg.separator != nil {
+		client.separator = *cfg.separator
+	}
+	client.suffix = cfg.suffix
+	if cfg.randSource != nil {
+		client.SetRandSource(cfg.randSource)
+	}
+	client.sampler = cfg.sampler
+	if cfg.coalesce != nil {
+		client.gaugeDeltas = newGaugeDeltas()
+		client.gaugeDeltas.start(*cfg.coalesce)
+	}
+	if cfg.guarantee != nil {
+		client.counts = newCounterTotals()
+		client.counts.start(*cfg.guarantee)
+	}
+
+	return client, nil
+}
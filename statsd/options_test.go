@@ -0,0 +1,405 @@
+package statsd
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewClientWithOptions(t *testing.T) {
+	l, err := newUDPListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	c, err := NewClientWithOptions(
+		WithAddr(l.LocalAddr().String()),
+		WithPrefix("test"),
+		WithDefaultRate(0.6),
+		// Float32() of this source is always 0.5
+		WithRandSource(constSource(1<<62)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	err = c.Inc("count", 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := make([]byte, 128)
+	n, _, err := l.ReadFrom(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "test.count:1|c|@0.6"
+	if string(data[:n]) != expected {
+		t.Fatalf("got '%s' expected '%s'", data[:n], expected)
+	}
+}
+
+func TestNewClientWithOptionsSender(t *testing.T) {
+	l, err := newUDPListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	sender, err := NewSimpleSender(l.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewClientWithOptions(WithSender(sender))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	err = c.Inc("count", 1, 1.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := make([]byte, 128)
+	n, _, err := l.ReadFrom(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "count:1|c"
+	if string(data[:n]) != expected {
+		t.Fatalf("got '%s' expected '%s'", data[:n], expected)
+	}
+}
+
+func TestNewClientWithOptionsErrors(t *testing.T) {
+	sender, err := NewSimpleSender("127.0.0.1:8125")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sender.Close()
+
+	_, err = NewClientWithOptions(WithAddr("127.0.0.1:8125"), WithSender(sender))
+	if err == nil {
+		t.Fatal("expected an error when both WithAddr and WithSender are set")
+	}
+
+	_, err = NewClientWithOptions(WithSender(sender), WithWriteTimeout(time.Second))
+	if err == nil {
+		t.Fatal("expected an error when both WithWriteTimeout and WithSender are set")
+	}
+
+	_, err = NewClientWithOptions(WithSender(sender), WithDefaultRate(1.5))
+	if err == nil {
+		t.Fatal("expected an error for an invalid default rate")
+	}
+
+	_, err = NewClientWithOptions(WithSender(sender), WithSampleRateGuarantee(0))
+	if err == nil {
+		t.Fatal("expected an error for a sample rate guarantee interval of 0")
+	}
+
+	_, err = NewClientWithOptions(WithSender(sender), WithTimingPrecision(10))
+	if err == nil {
+		t.Fatal("expected an error for a timing precision above 9")
+	}
+
+	_, err = NewClientWithOptions(WithSender(sender), WithTimingPrecision(-1))
+	if err == nil {
+		t.Fatal("expected an error for a negative timing precision")
+	}
+
+	_, err = NewClientWithOptions(WithSender(sender), WithMaxPacketSize(-1))
+	if err == nil {
+		t.Fatal("expected an error for a negative max packet size")
+	}
+
+	_, err = NewClientWithOptions(WithSender(sender), WithDryRun(true))
+	if err == nil {
+		t.Fatal("expected an error when both WithDryRun and WithSender are set")
+	}
+
+	_, err = NewClientWithOptions(WithPrefix("test"))
+	if err == nil {
+		t.Fatal("expected an error when neither WithAddr nor WithSender are set")
+	}
+}
+
+var prefixSeparatorTests = []struct {
+	Prefix    string
+	Separator *string
+	Expected  string
+}{
+	{"test", nil, "test.count:1|c"},
+	{"test.", nil, "test.count:1|c"},
+	{"test", strPtr("/"), "test/count:1|c"},
+	{"test/", strPtr("/"), "test/count:1|c"},
+	{"test", strPtr(""), "testcount:1|c"},
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestWithPrefixSeparator(t *testing.T) {
+	for _, tt := range prefixSeparatorTests {
+		rs := NewRecordingSender()
+		opts := []Option{WithSender(rs), WithPrefix(tt.Prefix)}
+		if tt.Separator != nil {
+			opts = append(opts, WithPrefixSeparator(*tt.Separator))
+		}
+		c, err := NewClientWithOptions(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = c.Inc("count", 1, 1.0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sent := rs.GetSent()
+		if len(sent) != 1 || string(sent[0]) != tt.Expected {
+			t.Fatalf("got '%s' expected '%s'", sent, tt.Expected)
+		}
+	}
+}
+
+var typeDefaultRateTests = []struct {
+	Method   string
+	Value    interface{}
+	Rate     float32
+	Expected string
+}{
+	{"Timing", int64(1), UseDefaultRate, "stat:1|ms|@0.1"},
+	{"Inc", int64(1), UseDefaultRate, "stat:1|c|@0.9"},
+	{"Gauge", int64(1), UseDefaultRate, "stat:1|g|@0.6"},
+	{"Gauge", int64(1), 0, "stat:1|g|@0.6"},
+	{"Timing", int64(1), 0.8, "stat:1|ms|@0.8"},
+	{"Raw", "1|ms", UseDefaultRate, "stat:1|ms|@0.1"},
+}
+
+func TestClientTypeDefaultRate(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(
+		WithSender(rs),
+		WithDefaultRate(0.6),
+		WithTypeDefaultRate("ms", 0.1),
+		WithTypeDefaultRate("c", 0.9),
+		// Float32() of this source is 0, so everything is sampled in
+		WithRandSource(constSource(0)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for _, tt := range typeDefaultRateTests {
+		rs.Clear()
+		method := reflect.ValueOf(c).MethodByName(tt.Method)
+		e := method.Call([]reflect.Value{
+			reflect.ValueOf("stat"),
+			reflect.ValueOf(tt.Value),
+			reflect.ValueOf(tt.Rate)})[0]
+		if err, _ := e.Interface().(error); err != nil {
+			t.Fatal(err)
+		}
+
+		sent := rs.GetSent()
+		if len(sent) != 1 || string(sent[0]) != tt.Expected {
+			t.Fatalf("%s got '%s' expected '%s'", tt.Method, sent, tt.Expected)
+		}
+	}
+}
+
+func TestClientUseDefaultRateWithoutDefaults(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	err = c.Inc("count", 1, UseDefaultRate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := rs.GetSent()
+	if len(sent) != 1 || string(sent[0]) != "count:1|c" {
+		t.Fatalf("got '%s' expected 'count:1|c'", sent)
+	}
+
+	_, err = NewClientWithOptions(WithSender(rs), WithTypeDefaultRate("ms", 2))
+	if err == nil {
+		t.Fatal("expected an error for an invalid type default rate")
+	}
+}
+
+func TestClientWithLogger(t *testing.T) {
+	var logged []string
+	c, err := NewClientWithOptions(
+		WithSender(discardSender{}),
+		WithPrefix("test"),
+		WithLogger(func(payload []byte) {
+			logged = append(logged, string(payload))
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.Inc("count", 1, 1.0)
+	c.Gauge("gauge", -1, 1.0)
+	b := c.NewBatch()
+	b.Inc("a", 1, 1.0)
+	b.Inc("b", 1, 1.0)
+	b.Submit()
+
+	expected := []string{
+		"test.count:1|c",
+		"test.gauge:0|g\ntest.gauge:-1|g",
+		"test.a:1|c\ntest.b:1|c",
+	}
+	if len(logged) != len(expected) {
+		t.Fatalf("got %q expected %q", logged, expected)
+	}
+	for i, e := range expected {
+		if logged[i] != e {
+			t.Fatalf("got %q expected %q", logged, expected)
+		}
+	}
+}
+
+func TestClientWithMaxPacketSize(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(
+		WithSender(rs),
+		WithPrefix("test"),
+		WithTags(Tag{"env", "prod"}),
+		WithMaxPacketSize(20),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// "test.a:1|c|#env:prod" is exactly 20 bytes
+	if err := c.Raw("a", "1|c", 1.0); err != nil {
+		t.Fatal(err)
+	}
+	err = c.Raw("ab", "1|c", 1.0)
+	if !errors.Is(err, ErrPacketTooLarge) {
+		t.Fatalf("got error '%v' expected '%v'", err, ErrPacketTooLarge)
+	}
+	err = c.Inc("ab", 1, 1.0)
+	if !errors.Is(err, ErrPacketTooLarge) {
+		t.Fatalf("got error '%v' expected '%v'", err, ErrPacketTooLarge)
+	}
+
+	sent := rs.GetSent()
+	if len(sent) != 1 || string(sent[0]) != "test.a:1|c|#env:prod" {
+		t.Fatalf("got '%s' expected '%s'", sent, "test.a:1|c|#env:prod")
+	}
+	if stats := c.Stats(); stats.Errors != 2 || stats.Sent != 1 {
+		t.Fatalf("got %d errors and %d sent expected 2 and 1", stats.Errors, stats.Sent)
+	}
+}
+
+func TestClientWithNewlineTerminator(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		rs := NewRecordingSender()
+		c, err := NewClientWithOptions(WithSender(rs), WithNewlineTerminator(enabled))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c.Raw("raw", "1|c", 1.0)
+		c.Gauge("gauge", -1, 1.0)
+
+		expected := []string{"raw:1|c", "gauge:0|g\ngauge:-1|g"}
+		if enabled {
+			expected = []string{"raw:1|c\n", "gauge:0|g\ngauge:-1|g\n"}
+		}
+		sent := rs.GetSent()
+		if len(sent) != len(expected) {
+			t.Fatalf("got %q expected %q", sent, expected)
+		}
+		for i, e := range expected {
+			if string(sent[i]) != e {
+				t.Fatalf("got %q expected %q", sent[i], e)
+			}
+		}
+		c.Close()
+	}
+}
+
+func TestClientWithSampleRateSuffix(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		rs := NewRecordingSender()
+		c, err := NewClientWithOptions(
+			WithSender(rs),
+			WithSampleRateSuffix(enabled),
+			// Float32() of this source is always 0.5
+			WithRandSource(constSource(1<<62)),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// sampled out
+		c.Inc("count", 1, 0.5)
+		// sampled in
+		c.Inc("count", 1, 0.6)
+
+		expected := "count:1|c|@0.6"
+		if !enabled {
+			expected = "count:1|c"
+		}
+		sent := rs.GetSent()
+		if len(sent) != 1 || string(sent[0]) != expected {
+			t.Fatalf("got '%s' expected '%s'", sent, expected)
+		}
+		c.Close()
+	}
+}
+
+func TestClientWithDryRun(t *testing.T) {
+	// the address is never dialed or resolved
+	c, err := NewClientWithOptions(
+		WithAddr("statsd.invalid:8125"),
+		WithPrefix("test"),
+		WithDryRun(true),
+		// Float32() of this source is always 0.5
+		WithRandSource(constSource(1<<62)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.Inc("count", 1, 1.0)
+	// sampled out
+	c.Inc("count", 1, 0.5)
+	c.NewSubStatter("sub").Gauge("gauge", 1, 1.0)
+
+	expected := []string{"test.count:1|c", "test.sub.gauge:1|g"}
+	sent := c.(*Client).Sent()
+	if len(sent) != len(expected) {
+		t.Fatalf("got '%s' expected '%s'", sent, expected)
+	}
+	for i, e := range expected {
+		if string(sent[i]) != e {
+			t.Fatalf("got '%s' expected '%s'", sent[i], e)
+		}
+	}
+	if stats := c.Stats(); stats.Sent != 2 || stats.SampledOut != 1 {
+		t.Fatalf("got %d sent and %d sampled out expected 2 and 1", stats.Sent, stats.SampledOut)
+	}
+}
@@ -0,0 +1,517 @@
+/*
+Package otelstatsd provides a statsd Statter that records to an OpenTelemetry
+metric.Meter instead of sending statsd packets, for migrating off statsd
+without changing instrumentation call sites.
+
+Counters and meters are recorded to an Int64Counter, gauges to a
+Float64ObservableGauge reporting the last value set, and timings,
+histograms and distributions to a Float64Histogram. Timings are recorded in
+milliseconds.
+
+Instrument names are the prefixed stat names, with characters that are not
+valid in instrument names replaced with '_'. Tags are recorded as
+attributes. Sample rates are ignored, as every value is recorded. Sets, Raw
+stats, batches, service checks and events are not recorded.
+*/
+package otelstatsd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cactus/go-statsd-client/statsd"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// gauge holds the last value of a gauge for each attribute set, reported by
+// the observable gauge's callback.
+type gauge struct {
+	mx     sync.Mutex
+	values map[attribute.Distinct]gaugeValue
+}
+
+type gaugeValue struct {
+	attrs attribute.Set
+	value float64
+}
+
+func (g *gauge) update(attrs attribute.Set, f func(float64) float64) {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+	k := attrs.Equivalent()
+	g.values[k] = gaugeValue{attrs: attrs, value: f(g.values[k].value)}
+}
+
+func (g *gauge) observe(_ context.Context, o metric.Float64Observer) error {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+	for _, v := range g.values {
+		o.Observe(v.value, metric.WithAttributeSet(v.attrs))
+	}
+	return nil
+}
+
+// instruments holds the instruments created for each name, shared between
+// a Client and the statters derived from it.
+type instruments struct {
+	meter      metric.Meter
+	mx         sync.Mutex
+	counters   map[string]metric.Int64Counter
+	histograms map[string]metric.Float64Histogram
+	gauges     map[string]*gauge
+}
+
+func (m *instruments) counter(name string) (metric.Int64Counter, error) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	if c, ok := m.counters[name]; ok {
+		return c, nil
+	}
+	c, err := m.meter.Int64Counter(name)
+	if err != nil {
+		return nil, err
+	}
+	m.counters[name] = c
+	return c, nil
+}
+
+func (m *instruments) histogram(name, unit string) (metric.Float64Histogram, error) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	if h, ok := m.histograms[name]; ok {
+		return h, nil
+	}
+	var opts []metric.Float64HistogramOption
+	if unit != "" {
+		opts = append(opts, metric.WithUnit(unit))
+	}
+	h, err := m.meter.Float64Histogram(name, opts...)
+	if err != nil {
+		return nil, err
+	}
+	m.histograms[name] = h
+	return h, nil
+}
+
+func (m *instruments) gauge(name string) (*gauge, error) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	if g, ok := m.gauges[name]; ok {
+		return g, nil
+	}
+	g := &gauge{values: make(map[attribute.Distinct]gaugeValue)}
+	_, err := m.meter.Float64ObservableGauge(name, metric.WithFloat64Callback(g.observe))
+	if err != nil {
+		return nil, err
+	}
+	m.gauges[name] = g
+	return g, nil
+}
+
+// Client is a statsd.Statter that records to an OpenTelemetry metric.Meter.
+type Client struct {
+	prefix   string
+	prefixMx sync.RWMutex
+	attrs    attribute.Set
+	ctx      context.Context
+	m        *instruments
+}
+
+// instrumentName translates a stat name into an instrument name.
+func (s *Client) instrumentName(stat string) string {
+	s.prefixMx.RLock()
+	prefix := s.prefix
+	s.prefixMx.RUnlock()
+	if prefix != "" {
+		stat = prefix + "." + stat
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9',
+			r == '_', r == '.', r == '-', r == '/':
+			return r
+		}
+		return '_'
+	}, stat)
+}
+
+func (s *Client) add(stat string, value int64) error {
+	c, err := s.m.counter(s.instrumentName(stat))
+	if err != nil {
+		return err
+	}
+	c.Add(s.ctx, value, metric.WithAttributeSet(s.attrs))
+	return nil
+}
+
+func (s *Client) record(stat string, value float64, unit string) error {
+	h, err := s.m.histogram(s.instrumentName(stat), unit)
+	if err != nil {
+		return err
+	}
+	h.Record(s.ctx, value, metric.WithAttributeSet(s.attrs))
+	return nil
+}
+
+func (s *Client) updateGauge(stat string, f func(float64) float64) error {
+	g, err := s.m.gauge(s.instrumentName(stat))
+	if err != nil {
+		return err
+	}
+	g.update(s.attrs, f)
+	return nil
+}
+
+// Increments a counter.
+// stat is a string name for the metric.
+// value is the integer value. It must not be negative, as OpenTelemetry
+// counters can't decrease.
+// rate is ignored.
+func (s *Client) Inc(stat string, value int64, rate float32) error {
+	if value < 0 {
+		return fmt.Errorf("otelstatsd: counter %q can't be decreased", stat)
+	}
+	return s.add(stat, value)
+}
+
+// Increments many counters, as for Inc, joining any errors together.
+// counts maps the string names of the metrics to their integer values.
+// rate is ignored.
+func (s *Client) IncMany(counts map[string]int64, rate float32) error {
+	var errs []error
+	for stat, value := range counts {
+		if err := s.Inc(stat, value, rate); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Decrementing is not supported by OpenTelemetry counters, so Dec returns an
+// error unless value is 0.
+func (s *Client) Dec(stat string, value int64, rate float32) error {
+	return s.Inc(stat, -value, rate)
+}
+
+// Sets a gauge.
+// stat is a string name for the metric.
+// value is the integer value.
+// rate is ignored.
+func (s *Client) Gauge(stat string, value int64, rate float32) error {
+	return s.GaugeFloat(stat, float64(value), rate)
+}
+
+// Adds a delta to a gauge.
+// stat is a string name for the metric.
+// value is the (positive or negative) change.
+// rate is ignored.
+func (s *Client) GaugeDelta(stat string, value int64, rate float32) error {
+	return s.GaugeDeltaFloat(stat, float64(value), rate)
+}
+
+// Sets a gauge.
+// stat is a string name for the metric.
+// value is the float value.
+// rate is ignored.
+func (s *Client) GaugeFloat(stat string, value float64, rate float32) error {
+	if invalid(value) {
+		return statsd.ErrInvalidValue
+	}
+	return s.updateGauge(stat, func(float64) float64 { return value })
+}
+
+// Sets a gauge. Values beyond 2^53 lose precision, as gauges are recorded
+// as float64.
+// stat is a string name for the metric.
+// value is the unsigned integer value.
+// rate is ignored.
+func (s *Client) GaugeUint64(stat string, value uint64, rate float32) error {
+	return s.GaugeFloat(stat, float64(value), rate)
+}
+
+// Sets a gauge to a time, as seconds since the Unix epoch.
+// stat is a string name for the metric.
+// t is the time.
+// rate is ignored.
+func (s *Client) GaugeTime(stat string, t time.Time, rate float32) error {
+	return s.GaugeFloat(stat, float64(t.Unix()), rate)
+}
+
+// Sets a gauge to a time, as milliseconds since the Unix epoch.
+// stat is a string name for the metric.
+// t is the time.
+// rate is ignored.
+func (s *Client) GaugeTimeMillis(stat string, t time.Time, rate float32) error {
+	return s.GaugeFloat(stat, float64(t.UnixMilli()), rate)
+}
+
+// Sets a gauge to the rate per second of count events over window.
+// stat is a string name for the metric.
+// count is the number of events, set as count / window.Seconds(). A window
+// that is not positive returns statsd.ErrInvalidValue.
+// rate is ignored.
+func (s *Client) RateGauge(stat string, count int64, window time.Duration, rate float32) error {
+	if window <= 0 {
+		return statsd.ErrInvalidValue
+	}
+	return s.GaugeFloat(stat, float64(count)/window.Seconds(), rate)
+}
+
+// Adds a delta to a gauge.
+// stat is a string name for the metric.
+// value is the (positive or negative) change.
+// rate is ignored.
+func (s *Client) GaugeDeltaFloat(stat string, value float64, rate float32) error {
+	if invalid(value) {
+		return statsd.ErrInvalidValue
+	}
+	return s.updateGauge(stat, func(v float64) float64 { return v + value })
+}
+
+// Records a timing in milliseconds.
+// stat is a string name for the metric.
+// delta is the time duration value in milliseconds
+// rate is ignored.
+func (s *Client) Timing(stat string, delta int64, rate float32) error {
+	return s.record(stat, float64(delta), "ms")
+}
+
+// Records a timing in milliseconds.
+// stat is a string name for the metric.
+// delta is the timing value as time.Duration
+// rate is ignored.
+func (s *Client) TimingDuration(stat string, delta time.Duration, rate float32) error {
+	return s.record(stat, float64(delta)/float64(time.Millisecond), "ms")
+}
+
+// Records a timing in milliseconds. As sample rates are ignored, every
+// timing is recorded.
+// stat is a string name for the metric.
+// delta is the timing value as time.Duration
+// rate is ignored.
+func (s *Client) SampledTiming(stat string, delta time.Duration, rate float32) error {
+	return s.TimingDuration(stat, delta, rate)
+}
+
+// Records many timings in milliseconds.
+// stat is a string name for the metric.
+// deltas are the timing values as time.Duration
+// rate is ignored.
+func (s *Client) TimingMulti(stat string, deltas []time.Duration, rate float32) error {
+	for _, d := range deltas {
+		if err := s.TimingDuration(stat, d, rate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sets a gauge to a duration in milliseconds.
+// stat is a string name for the metric.
+// delta is the timing value as time.Duration
+// rate is ignored.
+func (s *Client) TimingGauge(stat string, delta time.Duration, rate float32) error {
+	return s.GaugeFloat(stat, float64(delta)/float64(time.Millisecond), rate)
+}
+
+// Sets are not supported, so Set does nothing.
+func (s *Client) Set(stat string, value string, rate float32) error {
+	return nil
+}
+
+// Records a value in a histogram.
+// stat is a string name for the metric.
+// value is the integer value.
+// rate is ignored.
+func (s *Client) Histogram(stat string, value int64, rate float32) error {
+	return s.record(stat, float64(value), "")
+}
+
+// Records a value in a histogram.
+// stat is a string name for the metric.
+// value is the float value.
+// rate is ignored.
+func (s *Client) HistogramFloat(stat string, value float64, rate float32) error {
+	if invalid(value) {
+		return statsd.ErrInvalidValue
+	}
+	return s.record(stat, value, "")
+}
+
+// Records a value in a histogram.
+// stat is a string name for the metric.
+// value is the float value.
+// rate is ignored.
+func (s *Client) Distribution(stat string, value float64, rate float32) error {
+	if invalid(value) {
+		return statsd.ErrInvalidValue
+	}
+	return s.record(stat, value, "")
+}
+
+// Increments a counter.
+// stat is a string name for the metric.
+// value is the integer value.
+// rate is ignored.
+func (s *Client) Meter(stat string, value int64, rate float32) error {
+	return s.Inc(stat, value, rate)
+}
+
+// Returns a Timing started now, which records the elapsed time when sent.
+func (s *Client) NewTiming() statsd.Timing {
+	return statsd.StartTiming(s)
+}
+
+// Counter returns a Counter that records with the Client.
+func (s *Client) Counter(stat string, rate float32) statsd.Counter {
+	return statsd.NewCounter(s, stat, rate)
+}
+
+// BoundGauge returns a Gauge that records with the Client.
+func (s *Client) BoundGauge(stat string, rate float32) statsd.Gauge {
+	return statsd.NewGauge(s, stat, rate)
+}
+
+// Timer returns a Timer that records with the Client.
+func (s *Client) Timer(stat string, rate float32) statsd.Timer {
+	return statsd.NewTimer(s, stat, rate)
+}
+
+// Calls f, and records its duration as a timing.
+func (s *Client) Time(stat string, rate float32, f func()) error {
+	t := s.NewTiming()
+	f()
+	return t.Send(stat, rate)
+}
+
+// Returns a function that, if the goroutine is panicking, increments the
+// stat counter via the Client and then panics again with the same value.
+func (s *Client) Recover(stat string) func() {
+	return func() {
+		if r := recover(); r != nil {
+			s.Inc(stat, 1, 1.0)
+			panic(r)
+		}
+	}
+}
+
+// Batches are not supported, so NewBatch returns a Batch that does nothing.
+func (s *Client) NewBatch() *statsd.Batch {
+	return &statsd.Batch{}
+}
+
+// Custom metric types are not supported, so Custom does nothing.
+func (s *Client) Custom(stat string, value string, suffix string, rate float32) error {
+	return nil
+}
+
+// Raw stats are not supported, so Raw does nothing.
+func (s *Client) Raw(stat string, value string, rate float32) error {
+	return nil
+}
+
+// Service checks are not supported, so ServiceCheck does nothing.
+func (s *Client) ServiceCheck(name string, status int, tags ...statsd.Tag) error {
+	return nil
+}
+
+// Events are not supported, so Event does nothing.
+func (s *Client) Event(title, text string, tags ...statsd.Tag) error {
+	return nil
+}
+
+// Raw stats are not supported, so RawBytes does nothing.
+func (s *Client) RawBytes(stat []byte, value []byte, rate float32) error {
+	return nil
+}
+
+// Sets/Updates the prefix of instrument names.
+func (s *Client) SetPrefix(prefix string) {
+	s.prefixMx.Lock()
+	defer s.prefixMx.Unlock()
+	s.prefix = prefix
+}
+
+// Returns a Client that records with tags added as attributes.
+func (s *Client) WithTags(tags ...statsd.Tag) statsd.Statter {
+	kvs := s.attrs.ToSlice()
+	for _, t := range tags {
+		kvs = append(kvs, attribute.String(t.Key, t.Value))
+	}
+	c := s.derive()
+	c.attrs = attribute.NewSet(kvs...)
+	return c
+}
+
+// Returns a Client that prefixes instrument names with prefix.
+func (s *Client) NewSubStatter(prefix string) statsd.Statter {
+	c := s.derive()
+	if c.prefix != "" {
+		prefix = c.prefix + "." + prefix
+	}
+	c.prefix = prefix
+	return c
+}
+
+// Returns a Client that records with ctx.
+func (s *Client) WithContext(ctx context.Context) statsd.Statter {
+	c := s.derive()
+	c.ctx = ctx
+	return c
+}
+
+func (s *Client) derive() *Client {
+	s.prefixMx.RLock()
+	defer s.prefixMx.RUnlock()
+	return &Client{prefix: s.prefix, attrs: s.attrs, ctx: s.ctx, m: s.m}
+}
+
+// Flush does nothing, as export is handled by the meter provider.
+func (s *Client) Flush() error {
+	return nil
+}
+
+// Enabled returns true, as metrics are recorded.
+func (s *Client) Enabled() bool {
+	return true
+}
+
+// Stats returns zero ClientStats, as no packets are sent.
+func (s *Client) Stats() statsd.ClientStats {
+	return statsd.ClientStats{}
+}
+
+// PublishExpvar does nothing, as no packets are sent.
+func (s *Client) PublishExpvar(name string) {}
+
+// Close does nothing, as the meter provider is owned by the caller.
+func (s *Client) Close() error {
+	return nil
+}
+
+// Returns a new Client recording to meter, with stat names prefixed by
+// prefix.
+func New(meter metric.Meter, prefix string) *Client {
+	return &Client{
+		prefix: prefix,
+		ctx:    context.Background(),
+		m: &instruments{
+			meter:      meter,
+			counters:   make(map[string]metric.Int64Counter),
+			histograms: make(map[string]metric.Float64Histogram),
+			gauges:     make(map[string]*gauge),
+		},
+	}
+}
+
+// invalid reports whether value is NaN or infinite, which is rejected with
+// statsd.ErrInvalidValue before it is recorded.
+func invalid(value float64) bool {
+	return math.IsNaN(value) || math.IsInf(value, 0)
+}
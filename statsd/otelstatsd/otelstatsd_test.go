@@ -0,0 +1,104 @@
+package otelstatsd
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/cactus/go-statsd-client/statsd"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func collect(t *testing.T, r *sdkmetric.ManualReader) map[string]metricdata.Aggregation {
+	var rm metricdata.ResourceMetrics
+	if err := r.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	data := make(map[string]metricdata.Aggregation)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			data[m.Name] = m.Data
+		}
+	}
+	return data
+}
+
+func newTestClient() (*Client, *sdkmetric.ManualReader) {
+	r := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(r))
+	return New(mp.Meter("test"), "test"), r
+}
+
+func TestClient(t *testing.T) {
+	s, r := newTestClient()
+
+	s.Inc("requests:count", 2, 1.0)
+	s.Inc("requests:count", 3, 0.1)
+	s.Gauge("depth", 7, 1.0)
+	s.GaugeDelta("depth", -2, 1.0)
+	s.TimingDuration("time", 1500*time.Microsecond, 1.0)
+
+	data := collect(t, r)
+
+	sum, ok := data["test.requests_count"].(metricdata.Sum[int64])
+	if !ok || len(sum.DataPoints) != 1 || sum.DataPoints[0].Value != 5 {
+		t.Fatalf("got counter %+v expected 5", data["test.requests_count"])
+	}
+	g, ok := data["test.depth"].(metricdata.Gauge[float64])
+	if !ok || len(g.DataPoints) != 1 || g.DataPoints[0].Value != 5 {
+		t.Fatalf("got gauge %+v expected 5", data["test.depth"])
+	}
+	h, ok := data["test.time"].(metricdata.Histogram[float64])
+	if !ok || len(h.DataPoints) != 1 || h.DataPoints[0].Sum != 1.5 {
+		t.Fatalf("got histogram %+v expected 1.5", data["test.time"])
+	}
+}
+
+func TestClientWithTags(t *testing.T) {
+	s, r := newTestClient()
+
+	s.WithTags(statsd.Tag{Key: "env", Value: "prod"}).Inc("count", 1, 1.0)
+	s.Inc("count", 1, 1.0)
+
+	sum := collect(t, r)["test.count"].(metricdata.Sum[int64])
+	if len(sum.DataPoints) != 2 {
+		t.Fatalf("got %d data points expected 2", len(sum.DataPoints))
+	}
+	for _, dp := range sum.DataPoints {
+		v, ok := dp.Attributes.Value("env")
+		if ok && v.AsString() != "prod" {
+			t.Fatalf("got env '%s' expected 'prod'", v.AsString())
+		}
+	}
+}
+
+func TestClientDec(t *testing.T) {
+	s, _ := newTestClient()
+
+	if err := s.Dec("count", 1, 1.0); err == nil {
+		t.Fatal("expected an error decrementing a counter")
+	}
+}
+
+func TestClientInvalidFloat(t *testing.T) {
+	s, r := newTestClient()
+
+	for _, v := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		for name, f := range map[string]func(string, float64, float32) error{
+			"GaugeFloat":      s.GaugeFloat,
+			"GaugeDeltaFloat": s.GaugeDeltaFloat,
+			"HistogramFloat":  s.HistogramFloat,
+			"Distribution":    s.Distribution,
+		} {
+			if err := f("stat", v, 1.0); !errors.Is(err, statsd.ErrInvalidValue) {
+				t.Errorf("%s(%v) got %v expected ErrInvalidValue", name, v, err)
+			}
+		}
+	}
+	if data := collect(t, r); len(data) != 0 {
+		t.Fatalf("got %+v expected nothing recorded", data)
+	}
+}
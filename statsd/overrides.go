@@ -0,0 +1,61 @@
+package statsd
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// rateOverrides holds sample rates set at runtime for stat name prefixes.
+type rateOverrides struct {
+	// prefix to float32 rate
+	rates sync.Map
+	// number of rates, so lookups are skipped when there are none
+	n int32
+}
+
+// lookup returns the rate of the longest prefix of stat with an override.
+func (o *rateOverrides) lookup(stat string) (float32, bool) {
+	if o == nil || atomic.LoadInt32(&o.n) == 0 {
+		return 0, false
+	}
+	var rate float32
+	longest := -1
+	o.rates.Range(func(k, v interface{}) bool {
+		prefix := k.(string)
+		if len(prefix) > longest && strings.HasPrefix(stat, prefix) {
+			longest = len(prefix)
+			rate = v.(float32)
+		}
+		return true
+	})
+	return rate, longest >= 0
+}
+
+// SetRateOverride sets the sample rate used at runtime for stats whose names
+// start with prefix, when they are sent with a rate of 0 or UseDefaultRate,
+// such as to sample a subset of stats fully while debugging. The longest
+// matching prefix is used, and the override is shared with derived clients.
+// Names are matched as passed, without the client prefix.
+//
+// The precedence is the rate passed in the call, then a runtime override,
+// then the default for the metric type set with WithTypeDefaultRate, then the
+// one set with WithDefaultRate. A rate of 0 removes the override for prefix.
+func (s *Client) SetRateOverride(prefix string, rate float32) error {
+	if s == nil {
+		return nil
+	}
+	if rate == 0 {
+		if _, ok := s.overrides.rates.LoadAndDelete(prefix); ok {
+			atomic.AddInt32(&s.overrides.n, -1)
+		}
+		return nil
+	}
+	if err := validateRate(rate); err != nil {
+		return err
+	}
+	if _, ok := s.overrides.rates.Swap(prefix, rate); !ok {
+		atomic.AddInt32(&s.overrides.n, 1)
+	}
+	return nil
+}
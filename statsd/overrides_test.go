@@ -0,0 +1,70 @@
+package statsd
+
+import (
+	"testing"
+)
+
+func TestClientRateOverride(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(
+		WithSender(rs),
+		WithPrefix("test"),
+		WithDefaultRate(0.6),
+		WithTypeDefaultRate("ms", 0.1),
+		// Float32() of this source is 0, so everything is sampled in
+		WithRandSource(constSource(0)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	client := c.(*Client)
+
+	if err := client.SetRateOverride("api.", 0.5); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.SetRateOverride("api.users.", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.SetRateOverride("db", 1.5); err == nil {
+		t.Fatal("SetRateOverride did not fail with an invalid rate")
+	}
+
+	tests := []struct {
+		send     func() error
+		expected string
+	}{
+		{func() error { return c.Inc("api.hits", 1, UseDefaultRate) }, "test.api.hits:1|c|@0.5"},
+		{func() error { return c.Inc("api.users.hits", 1, 0) }, "test.api.users.hits:1|c"},
+		{func() error { return c.Timing("api.time", 1, UseDefaultRate) }, "test.api.time:1|ms|@0.5"},
+		{func() error { return c.Raw("api.raw", "1|c", UseDefaultRate) }, "test.api.raw:1|c|@0.5"},
+		// an explicit rate takes precedence over the override
+		{func() error { return c.Inc("api.hits", 1, 0.2) }, "test.api.hits:1|c|@0.2"},
+		{func() error { return c.Inc("other", 1, UseDefaultRate) }, "test.other:1|c|@0.6"},
+		{func() error { return c.Timing("other", 1, UseDefaultRate) }, "test.other:1|ms|@0.1"},
+		// derived clients share the overrides
+		{func() error { return c.NewSubStatter("sub").Inc("api.hits", 1, UseDefaultRate) }, "test.sub.api.hits:1|c|@0.5"},
+	}
+	for _, tt := range tests {
+		rs.Clear()
+		if err := tt.send(); err != nil {
+			t.Fatal(err)
+		}
+		sent := rs.GetSent()
+		if len(sent) != 1 || string(sent[0]) != tt.expected {
+			t.Fatalf("got '%s' expected '%s'", sent, tt.expected)
+		}
+	}
+
+	// a rate of 0 removes the override
+	if err := client.SetRateOverride("api.", 0); err != nil {
+		t.Fatal(err)
+	}
+	rs.Clear()
+	if err := c.Inc("api.hits", 1, UseDefaultRate); err != nil {
+		t.Fatal(err)
+	}
+	if sent := rs.GetSent(); len(sent) != 1 || string(sent[0]) != "test.api.hits:1|c|@0.6" {
+		t.Fatalf("got '%s' expected '%s'", sent, "test.api.hits:1|c|@0.6")
+	}
+}
@@ -0,0 +1,65 @@
+package statsd
+
+import (
+	"errors"
+	"net"
+	"sync/atomic"
+)
+
+// PooledSender provides a send interface to a UDP address over several
+// sockets, used in turn, so that concurrent sends do not contend for the
+// write lock of a single socket.
+type PooledSender struct {
+	senders []*SimpleSender
+	// incremented by each Send to pick the next socket
+	next uint64
+}
+
+// Send sends the data to the server endpoint, over the next socket of the
+// pool.
+func (s *PooledSender) Send(data []byte) (int, error) {
+	i := atomic.AddUint64(&s.next, 1)
+	return s.senders[i%uint64(len(s.senders))].Send(data)
+}
+
+// RemoteAddr returns the resolved address data is sent to.
+func (s *PooledSender) RemoteAddr() net.Addr {
+	return s.senders[0].RemoteAddr()
+}
+
+// Closes every socket of the pool, joining any errors together.
+// Later calls return nil, and Send returns ErrClosed once closed.
+func (s *PooledSender) Close() error {
+	var errs []error
+	for _, sender := range s.senders {
+		if err := sender.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Returns a new PooledSender for sending to the supplied address over
+// numConns sockets, and an error.
+//
+// addr is a string of the format "hostname:port", and must be parsable by
+// net.ResolveUDPAddr.
+func NewPooledSender(addr string, numConns int) (Sender, error) {
+	if numConns < 1 {
+		return nil, errors.New("Number of connections must be greater than 0")
+	}
+
+	senders := make([]*SimpleSender, 0, numConns)
+	for i := 0; i < numConns; i++ {
+		sender, err := NewSimpleSender(addr)
+		if err != nil {
+			for _, s := range senders {
+				s.Close()
+			}
+			return nil, err
+		}
+		senders = append(senders, sender.(*SimpleSender))
+	}
+
+	return &PooledSender{senders: senders}, nil
+}
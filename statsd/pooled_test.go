@@ -0,0 +1,81 @@
+package statsd
+
+import (
+	"net"
+	"testing"
+)
+
+func TestPooledSender(t *testing.T) {
+	l, err := newUDPListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	s, err := NewPooledSender(l.LocalAddr().String(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// each send uses the next socket of the pool
+	sources := make(map[string]bool)
+	data := make([]byte, 128)
+	for i := 0; i < 3; i++ {
+		if _, err := s.Send([]byte("test.count:1|c")); err != nil {
+			t.Fatal(err)
+		}
+		n, src, err := l.ReadFrom(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data[:n]) != "test.count:1|c" {
+			t.Fatalf("got '%s' expected '%s'", data[:n], "test.count:1|c")
+		}
+		sources[src.String()] = true
+	}
+	if len(sources) != 3 {
+		t.Fatalf("got %d source addresses expected 3", len(sources))
+	}
+}
+
+func TestPooledSenderErrors(t *testing.T) {
+	if _, err := NewPooledSender("127.0.0.1:8125", 0); err == nil {
+		t.Fatal("expected an error for 0 connections")
+	}
+	if _, err := NewPooledSender("invalid:address:8125", 2); err == nil {
+		t.Fatal("expected an error for an invalid address")
+	}
+}
+
+func benchmarkSenderParallel(b *testing.B, newSender func(addr string) (Sender, error)) {
+	l, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer l.Close()
+
+	s, err := newSender(l.LocalAddr().String())
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer s.Close()
+
+	data := []byte("test.count:1|c")
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			s.Send(data)
+		}
+	})
+}
+
+func BenchmarkSimpleSenderParallel(b *testing.B) {
+	benchmarkSenderParallel(b, NewSimpleSender)
+}
+
+func BenchmarkPooledSenderParallel(b *testing.B) {
+	benchmarkSenderParallel(b, func(addr string) (Sender, error) {
+		return NewPooledSender(addr, 8)
+	})
+}
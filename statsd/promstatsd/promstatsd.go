@@ -0,0 +1,396 @@
+/*
+Package promstatsd provides a statsd Statter that also records to Prometheus
+collectors, for migrating from statsd to Prometheus while metrics flow to
+both.
+
+Counters, gauges and timings are mirrored to a prometheus.Counter,
+prometheus.Gauge and prometheus.Histogram named after the stat. Stat names
+are translated to Prometheus metric names by replacing characters that are
+not valid in them, such as '.', with '_'. Timings are recorded in seconds,
+with the default buckets, in a histogram named with a "_seconds" suffix.
+
+Sets, Raw stats, batches, service checks and events are only sent to
+statsd. Tags are not mirrored as labels.
+*/
+package promstatsd
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cactus/go-statsd-client/statsd"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collectors holds the Prometheus collectors registered for each metric
+// name, shared between a Client and the statters derived from it.
+type collectors struct {
+	reg        prometheus.Registerer
+	mx         sync.Mutex
+	counters   map[string]prometheus.Counter
+	gauges     map[string]prometheus.Gauge
+	histograms map[string]prometheus.Histogram
+}
+
+// register registers c with the registerer, returning the already
+// registered collector if there is one. It returns nil if c can't be
+// registered, such as when the name is in use by a collector of another type.
+func (m *collectors) register(c prometheus.Collector) prometheus.Collector {
+	err := m.reg.Register(c)
+	if err == nil {
+		return c
+	}
+	var are prometheus.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		return are.ExistingCollector
+	}
+	return nil
+}
+
+func (m *collectors) counter(name string) prometheus.Counter {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	if c, ok := m.counters[name]; ok {
+		return c
+	}
+	c, _ := m.register(prometheus.NewCounter(prometheus.CounterOpts{
+		Name: name,
+		Help: "statsd counter " + name,
+	})).(prometheus.Counter)
+	m.counters[name] = c
+	return c
+}
+
+func (m *collectors) gauge(name string) prometheus.Gauge {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	if g, ok := m.gauges[name]; ok {
+		return g
+	}
+	g, _ := m.register(prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: name,
+		Help: "statsd gauge " + name,
+	})).(prometheus.Gauge)
+	m.gauges[name] = g
+	return g
+}
+
+func (m *collectors) histogram(name string) prometheus.Histogram {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	if h, ok := m.histograms[name]; ok {
+		return h
+	}
+	h, _ := m.register(prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    name,
+		Help:    "statsd histogram " + name,
+		Buckets: prometheus.DefBuckets,
+	})).(prometheus.Histogram)
+	m.histograms[name] = h
+	return h
+}
+
+// Client is a statsd.Statter that sends via another Statter, and mirrors
+// counters, gauges and timings to Prometheus collectors.
+type Client struct {
+	statsd.Statter
+	prefix string
+	m      *collectors
+}
+
+// metricName translates a stat name into a Prometheus metric name.
+func (s *Client) metricName(stat string) string {
+	if s.prefix != "" {
+		stat = s.prefix + "_" + stat
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9',
+			r == '_', r == ':':
+			return r
+		}
+		return '_'
+	}, stat)
+}
+
+// Increments a statsd count type, and adds value to the Prometheus counter.
+// Negative values are only sent to statsd, as Prometheus counters can't
+// decrease.
+func (s *Client) Inc(stat string, value int64, rate float32) error {
+	if value >= 0 {
+		if c := s.m.counter(s.metricName(stat)); c != nil {
+			c.Add(float64(value))
+		}
+	}
+	return s.Statter.Inc(stat, value, rate)
+}
+
+// Increments many statsd count types, and adds the values to the Prometheus
+// counters. Negative values are only sent to statsd.
+func (s *Client) IncMany(counts map[string]int64, rate float32) error {
+	for stat, value := range counts {
+		if value < 0 {
+			continue
+		}
+		if c := s.m.counter(s.metricName(stat)); c != nil {
+			c.Add(float64(value))
+		}
+	}
+	return s.Statter.IncMany(counts, rate)
+}
+
+// Decrements a statsd count type. As Prometheus counters can't decrease, it
+// is only sent to statsd.
+func (s *Client) Dec(stat string, value int64, rate float32) error {
+	return s.Statter.Dec(stat, value, rate)
+}
+
+// Submits/Updates a statsd gauge type, and sets the Prometheus gauge.
+func (s *Client) Gauge(stat string, value int64, rate float32) error {
+	if g := s.m.gauge(s.metricName(stat)); g != nil {
+		g.Set(float64(value))
+	}
+	return s.Statter.Gauge(stat, value, rate)
+}
+
+// Submits/Updates an unsigned statsd gauge type, and sets the Prometheus
+// gauge.
+func (s *Client) GaugeUint64(stat string, value uint64, rate float32) error {
+	if g := s.m.gauge(s.metricName(stat)); g != nil {
+		g.Set(float64(value))
+	}
+	return s.Statter.GaugeUint64(stat, value, rate)
+}
+
+// Submits/Updates a statsd gauge type with a time in seconds since the Unix
+// epoch, and sets the Prometheus gauge.
+func (s *Client) GaugeTime(stat string, t time.Time, rate float32) error {
+	return s.Gauge(stat, t.Unix(), rate)
+}
+
+// Submits/Updates a statsd gauge type with a time in milliseconds since the
+// Unix epoch, and sets the Prometheus gauge.
+func (s *Client) GaugeTimeMillis(stat string, t time.Time, rate float32) error {
+	return s.Gauge(stat, t.UnixMilli(), rate)
+}
+
+// Submits/Updates a statsd gauge type with the rate per second of count
+// events over window, and sets the Prometheus gauge. A window that is not
+// positive returns statsd.ErrInvalidValue.
+func (s *Client) RateGauge(stat string, count int64, window time.Duration, rate float32) error {
+	if window <= 0 {
+		return statsd.ErrInvalidValue
+	}
+	return s.GaugeFloat(stat, float64(count)/window.Seconds(), rate)
+}
+
+// Submits a delta to a statsd gauge, and adds it to the Prometheus gauge.
+func (s *Client) GaugeDelta(stat string, value int64, rate float32) error {
+	if g := s.m.gauge(s.metricName(stat)); g != nil {
+		g.Add(float64(value))
+	}
+	return s.Statter.GaugeDelta(stat, value, rate)
+}
+
+// Submits/Updates a float statsd gauge type, and sets the Prometheus gauge.
+func (s *Client) GaugeFloat(stat string, value float64, rate float32) error {
+	if invalid(value) {
+		return statsd.ErrInvalidValue
+	}
+	if g := s.m.gauge(s.metricName(stat)); g != nil {
+		g.Set(value)
+	}
+	return s.Statter.GaugeFloat(stat, value, rate)
+}
+
+// Submits a float delta to a statsd gauge, and adds it to the Prometheus
+// gauge.
+func (s *Client) GaugeDeltaFloat(stat string, value float64, rate float32) error {
+	if invalid(value) {
+		return statsd.ErrInvalidValue
+	}
+	if g := s.m.gauge(s.metricName(stat)); g != nil {
+		g.Add(value)
+	}
+	return s.Statter.GaugeDeltaFloat(stat, value, rate)
+}
+
+// Submits a statsd timing type, in milliseconds, and observes it in seconds
+// in the Prometheus histogram.
+func (s *Client) Timing(stat string, delta int64, rate float32) error {
+	s.observeDuration(stat, time.Duration(delta)*time.Millisecond)
+	return s.Statter.Timing(stat, delta, rate)
+}
+
+// Submits a statsd timing type, and observes it in seconds in the
+// Prometheus histogram.
+func (s *Client) TimingDuration(stat string, delta time.Duration, rate float32) error {
+	s.observeDuration(stat, delta)
+	return s.Statter.TimingDuration(stat, delta, rate)
+}
+
+// Submits a sampled statsd timing type, and observes every value in seconds
+// in the Prometheus histogram.
+func (s *Client) SampledTiming(stat string, delta time.Duration, rate float32) error {
+	s.observeDuration(stat, delta)
+	return s.Statter.SampledTiming(stat, delta, rate)
+}
+
+// Submits many statsd timings in a single line, and observes every value in
+// seconds in the Prometheus histogram.
+func (s *Client) TimingMulti(stat string, deltas []time.Duration, rate float32) error {
+	for _, d := range deltas {
+		s.observeDuration(stat, d)
+	}
+	return s.Statter.TimingMulti(stat, deltas, rate)
+}
+
+// Submits a statsd gauge type with a duration in milliseconds, and sets the
+// Prometheus gauge in seconds.
+func (s *Client) TimingGauge(stat string, delta time.Duration, rate float32) error {
+	if g := s.m.gauge(s.metricName(stat) + "_seconds"); g != nil {
+		g.Set(delta.Seconds())
+	}
+	return s.Statter.TimingGauge(stat, delta, rate)
+}
+
+func (s *Client) observeDuration(stat string, d time.Duration) {
+	if h := s.m.histogram(s.metricName(stat) + "_seconds"); h != nil {
+		h.Observe(d.Seconds())
+	}
+}
+
+// Submits a statsd histogram type, and observes it in the Prometheus
+// histogram.
+func (s *Client) Histogram(stat string, value int64, rate float32) error {
+	s.observe(stat, float64(value))
+	return s.Statter.Histogram(stat, value, rate)
+}
+
+// Submits a float statsd histogram type, and observes it in the Prometheus
+// histogram.
+func (s *Client) HistogramFloat(stat string, value float64, rate float32) error {
+	if invalid(value) {
+		return statsd.ErrInvalidValue
+	}
+	s.observe(stat, value)
+	return s.Statter.HistogramFloat(stat, value, rate)
+}
+
+// Submits a statsd distribution type, and observes it in the Prometheus
+// histogram.
+func (s *Client) Distribution(stat string, value float64, rate float32) error {
+	if invalid(value) {
+		return statsd.ErrInvalidValue
+	}
+	s.observe(stat, value)
+	return s.Statter.Distribution(stat, value, rate)
+}
+
+func (s *Client) observe(stat string, value float64) {
+	if h := s.m.histogram(s.metricName(stat)); h != nil {
+		h.Observe(value)
+	}
+}
+
+// Submits a statsd meter type, and adds value to the Prometheus counter.
+func (s *Client) Meter(stat string, value int64, rate float32) error {
+	if value >= 0 {
+		if c := s.m.counter(s.metricName(stat)); c != nil {
+			c.Add(float64(value))
+		}
+	}
+	return s.Statter.Meter(stat, value, rate)
+}
+
+// Returns a Timing started now, which is sent via the Client when sent.
+func (s *Client) NewTiming() statsd.Timing {
+	return statsd.StartTiming(s)
+}
+
+// Counter returns a Counter that sends via the Client.
+func (s *Client) Counter(stat string, rate float32) statsd.Counter {
+	return statsd.NewCounter(s, stat, rate)
+}
+
+// BoundGauge returns a Gauge that sends via the Client.
+func (s *Client) BoundGauge(stat string, rate float32) statsd.Gauge {
+	return statsd.NewGauge(s, stat, rate)
+}
+
+// Timer returns a Timer that sends via the Client.
+func (s *Client) Timer(stat string, rate float32) statsd.Timer {
+	return statsd.NewTimer(s, stat, rate)
+}
+
+// Calls f, and submits its duration as a timing via the Client.
+func (s *Client) Time(stat string, rate float32, f func()) error {
+	t := s.NewTiming()
+	f()
+	return t.Send(stat, rate)
+}
+
+// Returns a function that, if the goroutine is panicking, increments the
+// stat counter via the Client and then panics again with the same value.
+func (s *Client) Recover(stat string) func() {
+	return func() {
+		if r := recover(); r != nil {
+			s.Inc(stat, 1, 1.0)
+			panic(r)
+		}
+	}
+}
+
+// Enabled returns true, as metrics are recorded in Prometheus even when the
+// underlying Statter does not send them.
+func (s *Client) Enabled() bool {
+	return true
+}
+
+// Returns a Client sending with the tags added, sharing the Prometheus
+// collectors.
+func (s *Client) WithTags(tags ...statsd.Tag) statsd.Statter {
+	return &Client{Statter: s.Statter.WithTags(tags...), prefix: s.prefix, m: s.m}
+}
+
+// Returns a Client that prefixes stat names with prefix, for both statsd and
+// Prometheus metric names.
+func (s *Client) NewSubStatter(prefix string) statsd.Statter {
+	p := prefix
+	if s.prefix != "" {
+		p = s.prefix + "_" + prefix
+	}
+	return &Client{Statter: s.Statter.NewSubStatter(prefix), prefix: p, m: s.m}
+}
+
+// Returns a Client sending with ctx, sharing the Prometheus collectors.
+func (s *Client) WithContext(ctx context.Context) statsd.Statter {
+	return &Client{Statter: s.Statter.WithContext(ctx), prefix: s.prefix, m: s.m}
+}
+
+// Returns a new Client sending via statter, and registering collectors with
+// reg. If reg is nil, prometheus.DefaultRegisterer is used.
+func New(statter statsd.Statter, reg prometheus.Registerer) *Client {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	return &Client{
+		Statter: statter,
+		m: &collectors{
+			reg:        reg,
+			counters:   make(map[string]prometheus.Counter),
+			gauges:     make(map[string]prometheus.Gauge),
+			histograms: make(map[string]prometheus.Histogram),
+		},
+	}
+}
+
+// invalid reports whether value is NaN or infinite, which is rejected with
+// statsd.ErrInvalidValue before it is recorded.
+func invalid(value float64) bool {
+	return math.IsNaN(value) || math.IsInf(value, 0)
+}
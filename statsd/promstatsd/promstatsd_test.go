@@ -0,0 +1,85 @@
+package promstatsd
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/cactus/go-statsd-client/statsd"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func newTestClient(t *testing.T) (*Client, *statsd.RecordingSender) {
+	rs := statsd.NewRecordingSender()
+	c, err := statsd.NewClientWithOptions(statsd.WithSender(rs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	return New(c, prometheus.NewRegistry()), rs
+}
+
+func TestClient(t *testing.T) {
+	s, rs := newTestClient(t)
+
+	s.Inc("requests.count", 2, 1.0)
+	s.Inc("requests.count", 3, 1.0)
+	s.Gauge("queue.depth", 7, 1.0)
+	s.GaugeDelta("queue.depth", -2, 1.0)
+	s.TimingDuration("request.time", 250*time.Millisecond, 1.0)
+
+	if v := testutil.ToFloat64(s.m.counters["requests_count"]); v != 5 {
+		t.Fatalf("got counter %v expected 5", v)
+	}
+	if v := testutil.ToFloat64(s.m.gauges["queue_depth"]); v != 5 {
+		t.Fatalf("got gauge %v expected 5", v)
+	}
+	if n := testutil.CollectAndCount(s.m.histograms["request_time_seconds"]); n != 1 {
+		t.Fatalf("got %d histograms expected 1", n)
+	}
+
+	if n := len(rs.GetSent()); n != 5 {
+		t.Fatalf("got %d statsd packets expected 5", n)
+	}
+}
+
+func TestClientSubStatter(t *testing.T) {
+	s, rs := newTestClient(t)
+
+	sub := s.NewSubStatter("api").(*Client)
+	sub.Inc("count", 1, 1.0)
+
+	if v := testutil.ToFloat64(s.m.counters["api_count"]); v != 1 {
+		t.Fatalf("got counter %v expected 1", v)
+	}
+	sent := rs.GetSent()
+	if len(sent) != 1 || string(sent[0]) != "api.count:1|c" {
+		t.Fatalf("got '%s' expected 'api.count:1|c'", sent)
+	}
+}
+
+func TestClientInvalidFloat(t *testing.T) {
+	s, rs := newTestClient(t)
+
+	for _, v := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		for name, f := range map[string]func(string, float64, float32) error{
+			"GaugeFloat":      s.GaugeFloat,
+			"GaugeDeltaFloat": s.GaugeDeltaFloat,
+			"HistogramFloat":  s.HistogramFloat,
+			"Distribution":    s.Distribution,
+		} {
+			if err := f("stat", v, 1.0); !errors.Is(err, statsd.ErrInvalidValue) {
+				t.Errorf("%s(%v) got %v expected ErrInvalidValue", name, v, err)
+			}
+		}
+	}
+	if len(s.m.gauges) != 0 || len(s.m.histograms) != 0 {
+		t.Fatalf("got gauges %v histograms %v expected no collectors", s.m.gauges, s.m.histograms)
+	}
+	if sent := rs.GetSent(); len(sent) != 0 {
+		t.Fatalf("got '%s' expected nothing sent", sent)
+	}
+}
@@ -0,0 +1,151 @@
+package statsd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrRateLimited is returned by a RateLimitedSender for data dropped because
+// the limit for the current second was reached. A Client counts data dropped
+// this way in the Dropped of Stats, and only reports it to the function set
+// with WithOnError, so its methods still return nil.
+var ErrRateLimited = errors.New("statsd: rate limit exceeded")
+
+// RateLimitedSender provides a send interface that caps the number of sends
+// per second to another Sender, as a safety valve against flooding the
+// server. Sends over the limit are dropped.
+type RateLimitedSender struct {
+	sender   Sender
+	limit    int64
+	count    int64
+	dropped  uint64
+	closed   int32
+	shutdown chan bool
+	done     chan bool
+	once     sync.Once
+}
+
+// allow reports whether a send fits in the limit for the current second,
+// counting it as dropped if not.
+func (s *RateLimitedSender) allow() error {
+	if atomic.LoadInt32(&s.closed) != 0 {
+		return ErrClosed
+	}
+	if atomic.AddInt64(&s.count, 1) > s.limit {
+		atomic.AddUint64(&s.dropped, 1)
+		return ErrRateLimited
+	}
+	return nil
+}
+
+// Send sends the data to the underlying sender, or drops it and returns
+// ErrRateLimited if the limit for the current second has been reached.
+func (s *RateLimitedSender) Send(data []byte) (int, error) {
+	if err := s.allow(); err != nil {
+		return 0, err
+	}
+	return s.sender.Send(data)
+}
+
+// SendContext is as Send, honoring ctx if the underlying sender does.
+func (s *RateLimitedSender) SendContext(ctx context.Context, data []byte) (int, error) {
+	if err := s.allow(); err != nil {
+		return 0, err
+	}
+	if cs, ok := s.sender.(contextSender); ok {
+		return cs.SendContext(ctx, data)
+	}
+	return s.sender.Send(data)
+}
+
+// Flush flushes the underlying sender, if it buffers data.
+func (s *RateLimitedSender) Flush() error {
+	if f, ok := s.sender.(flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// RemoteAddr returns the address the underlying sender sends to.
+func (s *RateLimitedSender) RemoteAddr() net.Addr {
+	if a, ok := s.sender.(Addressable); ok {
+		return a.RemoteAddr()
+	}
+	return nil
+}
+
+// Reconnect reconnects the underlying sender, if it implements Reconnecter.
+func (s *RateLimitedSender) Reconnect() error {
+	if r, ok := s.sender.(Reconnecter); ok {
+		return r.Reconnect()
+	}
+	return nil
+}
+
+// Ping pings the underlying sender, if it implements Pinger.
+func (s *RateLimitedSender) Ping() error {
+	if p, ok := s.sender.(Pinger); ok {
+		return p.Ping()
+	}
+	return errNoPinger
+}
+
+// Dropped returns the number of sends dropped by the limit, plus any dropped
+// by the underlying sender.
+func (s *RateLimitedSender) Dropped() uint64 {
+	n := atomic.LoadUint64(&s.dropped)
+	if dc, ok := s.sender.(dropCounter); ok {
+		n += dc.Dropped()
+	}
+	return n
+}
+
+// Close stops resetting the limit, and closes the underlying sender.
+// Later calls return nil, and Send returns ErrClosed once closed.
+func (s *RateLimitedSender) Close() error {
+	var err error
+	s.once.Do(func() {
+		atomic.StoreInt32(&s.closed, 1)
+		close(s.shutdown)
+		<-s.done
+		err = s.sender.Close()
+	})
+	return err
+}
+
+// run resets the count of sends every second until closed.
+func (s *RateLimitedSender) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			atomic.StoreInt64(&s.count, 0)
+		case <-s.shutdown:
+			return
+		}
+	}
+}
+
+// Returns a new RateLimitedSender, allowing up to perSecond sends per second
+// to sender.
+func NewRateLimitedSender(sender Sender, perSecond int) (Sender, error) {
+	if perSecond <= 0 {
+		return nil, errors.New("Rate limit must be greater than 0")
+	}
+
+	s := &RateLimitedSender{
+		sender:   sender,
+		limit:    int64(perSecond),
+		shutdown: make(chan bool),
+		done:     make(chan bool),
+	}
+
+	go s.run()
+	return s, nil
+}
@@ -0,0 +1,130 @@
+package statsd
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestRateLimitedSender(t *testing.T) {
+	rs := NewRecordingSender()
+	s, err := NewRateLimitedSender(rs, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for i := 0; i < 5; i++ {
+		_, err := s.Send([]byte("test.count:1|c"))
+		if i < 2 && err != nil {
+			t.Fatal(err)
+		}
+		if i >= 2 && err != ErrRateLimited {
+			t.Fatalf("got error '%v' expected '%v'", err, ErrRateLimited)
+		}
+	}
+
+	if n := len(rs.GetSent()); n != 2 {
+		t.Fatalf("got %d sent expected 2", n)
+	}
+	if n := s.(*RateLimitedSender).Dropped(); n != 3 {
+		t.Fatalf("got %d dropped expected 3", n)
+	}
+}
+
+// connSender is a Sender implementing the optional sender interfaces,
+// counting calls to them.
+type connSender struct {
+	*RecordingSender
+	reconnects int
+	pings      int
+}
+
+func (s *connSender) RemoteAddr() net.Addr {
+	return &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8125}
+}
+
+func (s *connSender) Reconnect() error {
+	s.reconnects++
+	return nil
+}
+
+func (s *connSender) Ping() error {
+	s.pings++
+	return nil
+}
+
+func TestRateLimitedSenderForwards(t *testing.T) {
+	cs := &connSender{RecordingSender: NewRecordingSender()}
+	c, err := NewClientWithOptions(WithSender(cs), WithRateLimit(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.(*Client).Reconnect(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.(*Client).Ping(); err != nil {
+		t.Fatal(err)
+	}
+	if cs.reconnects != 1 || cs.pings != 1 {
+		t.Fatalf("got %d reconnects %d pings expected 1 each", cs.reconnects, cs.pings)
+	}
+	if sent := cs.GetSent(); len(sent) != 0 {
+		t.Fatalf("got '%s' expected the ping not sent as a metric", sent)
+	}
+	a, ok := c.(*Client).sender.(Addressable)
+	if !ok || a.RemoteAddr().String() != "127.0.0.1:8125" {
+		t.Fatal("expected the remote address of the underlying sender")
+	}
+
+	// without a Pinger underneath, Ping falls back to a metric
+	rs := NewRecordingSender()
+	c2, err := NewClientWithOptions(WithSender(rs), WithRateLimit(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Close()
+	if err := c2.(*Client).Ping(); err != nil {
+		t.Fatal(err)
+	}
+	if sent := rs.GetSent(); len(sent) != 1 || string(sent[0]) != "health.ping:0|c" {
+		t.Fatalf("got '%s' expected 'health.ping:0|c'", sent)
+	}
+}
+
+func TestClientRateLimit(t *testing.T) {
+	rs := NewRecordingSender()
+	var handled []error
+	c, err := NewClientWithOptions(
+		WithSender(rs),
+		WithRateLimit(2),
+		WithOnError(func(err error) { handled = append(handled, err) }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for i := 0; i < 5; i++ {
+		if err := c.Inc("count", 1, 1.0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := ClientStats{Sent: 2, BytesSent: 18, Dropped: 3}
+	if stats := c.Stats(); stats != expected {
+		t.Fatalf("got %+v expected %+v", stats, expected)
+	}
+	if len(handled) != 3 || !errors.Is(handled[0], ErrRateLimited) {
+		t.Fatalf("got handled errors %v expected 3 of '%v'", handled, ErrRateLimited)
+	}
+}
+
+func TestClientRateLimitInvalid(t *testing.T) {
+	_, err := NewClientWithOptions(WithSender(NewRecordingSender()), WithRateLimit(-1))
+	if err == nil {
+		t.Fatal("expected an error for a negative rate limit")
+	}
+}
@@ -0,0 +1,45 @@
+package statsd
+
+import "sync"
+
+// RecordingSender is a Sender that records every payload sent to it instead
+// of sending it anywhere. It is intended for asserting the exact wire output
+// of instrumented code in tests, without networking.
+type RecordingSender struct {
+	mx   sync.Mutex
+	sent [][]byte
+}
+
+// Send records a copy of the data.
+func (s *RecordingSender) Send(data []byte) (int, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.sent = append(s.sent, append([]byte(nil), data...))
+	return len(data), nil
+}
+
+// GetSent returns the payloads sent so far, in order.
+func (s *RecordingSender) GetSent() [][]byte {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	sent := make([][]byte, len(s.sent))
+	copy(sent, s.sent)
+	return sent
+}
+
+// Clear discards the recorded payloads.
+func (s *RecordingSender) Clear() {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.sent = nil
+}
+
+// Close does nothing. Recorded payloads remain available.
+func (s *RecordingSender) Close() error {
+	return nil
+}
+
+// Returns a new RecordingSender.
+func NewRecordingSender() *RecordingSender {
+	return &RecordingSender{}
+}
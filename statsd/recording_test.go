@@ -0,0 +1,33 @@
+package statsd
+
+import (
+	"testing"
+)
+
+func TestRecordingSender(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithPrefix("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	expected := []string{"test.count:1|c", "test.gauge:2|g"}
+	c.Inc("count", 1, 1.0)
+	c.Gauge("gauge", 2, 1.0)
+
+	sent := rs.GetSent()
+	if len(sent) != len(expected) {
+		t.Fatalf("got %d payloads expected %d", len(sent), len(expected))
+	}
+	for i, e := range expected {
+		if string(sent[i]) != e {
+			t.Fatalf("got '%s' expected '%s'", sent[i], e)
+		}
+	}
+
+	rs.Clear()
+	if len(rs.GetSent()) != 0 {
+		t.Fatal("expected no payloads after Clear")
+	}
+}
@@ -0,0 +1,257 @@
+package statsd
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// repeatMaxMetrics is the most distinct metrics a repeatSender holds per
+// window. Further metrics are sent as is.
+const repeatMaxMetrics = 1024
+
+// repeated is an identical metric sent n times within a window.
+type repeated struct {
+	name  string
+	value string
+	typ   string
+	rate  float32
+	tags  string
+	n     int64
+}
+
+// repeatSender collapses metrics repeated with the same stat, value and type
+// within a window into one, for WithRepeatCompression. Counters are sent as
+// their sum, and timings, histograms and distributions as the value once,
+// with the sample rate divided by the number of repeats, so that the server
+// counts each of them. Sets are sent once. Gauges, and lines in other
+// formats, are sent as is, as the order of gauge deltas and resets matters.
+type repeatSender struct {
+	sender  Sender
+	mx      sync.Mutex
+	pending map[string]*repeated
+	order   []string
+	// whether payloads are terminated by a newline, as with
+	// WithNewlineTerminator, for the metrics sent at the end of the window
+	newline  bool
+	closed   int32
+	shutdown chan bool
+	done     chan bool
+	once     sync.Once
+}
+
+// Send holds each metric in data that may be collapsed until the end of the
+// window, and sends the others at once.
+func (s *repeatSender) Send(data []byte) (int, error) {
+	if atomic.LoadInt32(&s.closed) != 0 {
+		return 0, ErrClosed
+	}
+
+	newline := bytes.HasSuffix(data, []byte("\n"))
+	var rest [][]byte
+	s.mx.Lock()
+	if newline {
+		s.newline = true
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(line) > 0 && !s.hold(string(line)) {
+			rest = append(rest, line)
+		}
+	}
+	s.mx.Unlock()
+
+	if len(rest) == 0 {
+		return len(data), nil
+	}
+	out := bytes.Join(rest, []byte("\n"))
+	if newline {
+		out = append(out, '\n')
+	}
+	if _, err := s.sender.Send(out); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// hold adds line to the pending metrics, reporting false if it must be sent
+// as is. Must be called with the mutex held.
+func (s *repeatSender) hold(line string) bool {
+	head, tags := line, ""
+	if i := strings.Index(line, "|#"); i >= 0 {
+		head, tags = line[:i], line[i:]
+	}
+	name, rest, ok := strings.Cut(head, ":")
+	if !ok || strings.Contains(rest, ":") {
+		return false
+	}
+	fields := strings.Split(rest, "|")
+	if len(fields) < 2 || len(fields) > 3 {
+		return false
+	}
+	switch fields[1] {
+	case "c", "ms", "h", "d", "s":
+	default:
+		return false
+	}
+	if fields[1] == "c" {
+		if _, err := strconv.ParseInt(fields[0], 10, 64); err != nil {
+			return false
+		}
+	}
+	rate := float32(1)
+	if len(fields) == 3 {
+		r, err := strconv.ParseFloat(strings.TrimPrefix(fields[2], "@"), 32)
+		if !strings.HasPrefix(fields[2], "@") || err != nil {
+			return false
+		}
+		rate = float32(r)
+	}
+
+	if r, ok := s.pending[line]; ok {
+		r.n++
+		return true
+	}
+	if len(s.pending) >= repeatMaxMetrics {
+		return false
+	}
+	s.pending[line] = &repeated{name: name, value: fields[0], typ: fields[1], rate: rate, tags: tags, n: 1}
+	s.order = append(s.order, line)
+	return true
+}
+
+// format appends the collapsed metric to buf.
+func (r *repeated) format(buf []byte) []byte {
+	buf = append(buf, r.name...)
+	buf = append(buf, ':')
+	rate := r.rate
+	switch r.typ {
+	case "c":
+		v, _ := strconv.ParseInt(r.value, 10, 64)
+		buf = strconv.AppendInt(buf, v*r.n, 10)
+	case "s":
+		buf = append(buf, r.value...)
+	default:
+		buf = append(buf, r.value...)
+		rate /= float32(r.n)
+	}
+	buf = append(buf, '|')
+	buf = append(buf, r.typ...)
+	if rate < 1 {
+		buf = append(buf, "|@"...)
+		buf = strconv.AppendFloat(buf, float64(rate), 'g', -1, 32)
+	}
+	return append(buf, r.tags...)
+}
+
+// flush sends every pending metric, in the order first sent, joining any
+// errors together.
+func (s *repeatSender) flush() error {
+	s.mx.Lock()
+	pending, order, newline := s.pending, s.order, s.newline
+	s.pending = make(map[string]*repeated)
+	s.order = nil
+	s.mx.Unlock()
+
+	var errs []error
+	var buf []byte
+	for _, key := range order {
+		buf = pending[key].format(buf[:0])
+		if newline {
+			buf = append(buf, '\n')
+		}
+		if _, err := s.sender.Send(buf); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Flush sends the pending metrics, then flushes the underlying sender, if it
+// buffers data.
+func (s *repeatSender) Flush() error {
+	err := s.flush()
+	if f, ok := s.sender.(flusher); ok {
+		err = errors.Join(err, f.Flush())
+	}
+	return err
+}
+
+// RemoteAddr returns the address the underlying sender sends to.
+func (s *repeatSender) RemoteAddr() net.Addr {
+	if a, ok := s.sender.(Addressable); ok {
+		return a.RemoteAddr()
+	}
+	return nil
+}
+
+// Reconnect reconnects the underlying sender, if it implements Reconnecter.
+func (s *repeatSender) Reconnect() error {
+	if r, ok := s.sender.(Reconnecter); ok {
+		return r.Reconnect()
+	}
+	return nil
+}
+
+// Ping pings the underlying sender, if it implements Pinger.
+func (s *repeatSender) Ping() error {
+	if p, ok := s.sender.(Pinger); ok {
+		return p.Ping()
+	}
+	return errNoPinger
+}
+
+// Dropped returns the number of sends dropped by the underlying sender.
+func (s *repeatSender) Dropped() uint64 {
+	if dc, ok := s.sender.(dropCounter); ok {
+		return dc.Dropped()
+	}
+	return 0
+}
+
+// Close stops the window, sends the pending metrics, and closes the
+// underlying sender. Later calls return nil, and Send returns ErrClosed once
+// closed.
+func (s *repeatSender) Close() error {
+	var err error
+	s.once.Do(func() {
+		atomic.StoreInt32(&s.closed, 1)
+		close(s.shutdown)
+		<-s.done
+		err = errors.Join(s.flush(), s.sender.Close())
+	})
+	return err
+}
+
+// run sends the pending metrics every window until closed. Errors are
+// returned to the client only from Flush and Close.
+func (s *repeatSender) run(window time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(window)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.flush()
+		case <-s.shutdown:
+			return
+		}
+	}
+}
+
+// newRepeatSender returns a started repeatSender collapsing metrics repeated
+// within window, sending to sender.
+func newRepeatSender(sender Sender, window time.Duration) *repeatSender {
+	s := &repeatSender{
+		sender:   sender,
+		pending:  make(map[string]*repeated),
+		shutdown: make(chan bool),
+		done:     make(chan bool),
+	}
+	go s.run(window)
+	return s
+}
@@ -0,0 +1,102 @@
+package statsd
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestClientRepeatCompression(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithPrefix("test"), WithRepeatCompression(time.Hour),
+		WithRandSource(constSource(0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for i := 0; i < 4; i++ {
+		c.Inc("count", 2, 1.0)
+		c.Timing("timing", 12, 1.0)
+		c.Timing("sampled", 5, 0.5)
+		c.Set("users", "alice", 1.0)
+	}
+	c.Inc("count", 3, 1.0)
+	c.Gauge("gauge", 1, 1.0)
+	c.Gauge("gauge", 1, 1.0)
+
+	// gauges are sent at once, and as is
+	expected := []string{"test.gauge:1|g", "test.gauge:1|g"}
+	assertSent(t, rs, expected)
+
+	rs.Clear()
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{
+		"test.count:8|c",
+		"test.timing:12|ms|@0.25",
+		"test.sampled:5|ms|@0.125",
+		"test.users:alice|s",
+		"test.count:3|c",
+	}
+	assertSent(t, rs, expected)
+
+	rs.Clear()
+	c.Inc("count", 1, 1.0)
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	assertSent(t, rs, []string{"test.count:1|c"})
+}
+
+func TestRepeatSenderBounded(t *testing.T) {
+	rs := NewRecordingSender()
+	s := newRepeatSender(rs, time.Hour)
+	defer s.Close()
+
+	for i := 0; i < repeatMaxMetrics; i++ {
+		s.Send([]byte("count" + strconv.Itoa(i) + ":1|c"))
+	}
+	if sent := rs.GetSent(); len(sent) != 0 {
+		t.Fatalf("got '%s' expected metrics held", sent)
+	}
+
+	// past the bound, new metrics are sent as is
+	s.Send([]byte("other:1|c"))
+	assertSent(t, rs, []string{"other:1|c"})
+	// and held ones are still collapsed
+	s.Send([]byte("count0:1|c"))
+	assertSent(t, rs, []string{"other:1|c"})
+}
+
+func TestClientRepeatCompressionNewline(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithNewlineTerminator(true), WithRepeatCompression(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.Inc("a", 1, 1.0)
+	c.Inc("a", 1, 1.0)
+	c.Gauge("g", 1, 1.0)
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	assertSent(t, rs, []string{"g:1|g\n", "a:2|c\n"})
+}
+
+// assertSent fails the test unless rs recorded exactly expected, in order.
+func assertSent(t *testing.T, rs *RecordingSender, expected []string) {
+	t.Helper()
+	sent := rs.GetSent()
+	if len(sent) != len(expected) {
+		t.Fatalf("got '%s' expected '%s'", sent, expected)
+	}
+	for i, e := range expected {
+		if string(sent[i]) != e {
+			t.Fatalf("got '%s' expected '%s'", sent[i], e)
+		}
+	}
+}
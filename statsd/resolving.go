@@ -0,0 +1,130 @@
+package statsd
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// resolveUDPAddr is net.ResolveUDPAddr, replaceable in tests.
+var resolveUDPAddr = net.ResolveUDPAddr
+
+// ResolvingSimpleSender provides a socket send interface, like SimpleSender,
+// that periodically re-resolves the hostname of the remote address. This
+// suits long lived clients sending to a host whose address changes over time.
+type ResolvingSimpleSender struct {
+	// underlying connection
+	c net.PacketConn
+	// address as supplied, re-resolved every interval
+	addr string
+	// resolved udp address, guarded by mx
+	ra        *net.UDPAddr
+	mx        sync.RWMutex
+	interval  time.Duration
+	shutdown  chan bool
+	done      chan bool
+	closeOnce sync.Once
+}
+
+// Send sends the data to the most recently resolved server endpoint.
+func (s *ResolvingSimpleSender) Send(data []byte) (int, error) {
+	s.mx.RLock()
+	ra := s.ra
+	s.mx.RUnlock()
+
+	n, err := s.c.(*net.UDPConn).WriteToUDP(data, ra)
+	if err != nil {
+		return 0, udpErr(err, len(data), ra)
+	}
+	if n == 0 {
+		return n, errors.New("Wrote no bytes")
+	}
+	return n, nil
+}
+
+// RemoteAddr returns the most recently resolved address data is sent to.
+func (s *ResolvingSimpleSender) RemoteAddr() net.Addr {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	return s.ra
+}
+
+// Closes ResolvingSimpleSender, stopping re-resolution.
+// Later calls return nil, and Send returns ErrClosed once closed.
+func (s *ResolvingSimpleSender) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.shutdown)
+		<-s.done
+	})
+	return closeConn(s.c)
+}
+
+// Start ResolvingSimpleSender
+// Re-resolves the address every interval until closed.
+func (s *ResolvingSimpleSender) Start() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ra, err := resolveUDPAddr("udp", s.addr)
+			if err != nil {
+				// transient resolution failures keep the last good address
+				continue
+			}
+			s.mx.Lock()
+			s.ra = ra
+			s.mx.Unlock()
+		case <-s.shutdown:
+			return
+		}
+	}
+}
+
+// Returns a new Sender for sending to the supplied address, re-resolving the
+// hostname every interval.
+//
+// addr is a string of the format "hostname:port", and must be parsable by
+// net.ResolveUDPAddr. If the host is a literal IP address, it is never
+// re-resolved and a SimpleSender is returned.
+//
+// If interval is 0, defaults to 30 seconds.
+func NewResolvingSimpleSender(addr string, interval time.Duration) (Sender, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, resolveErr(addr, err)
+	}
+	if net.ParseIP(host) != nil {
+		return NewSimpleSender(addr)
+	}
+
+	if interval <= time.Duration(0) {
+		interval = 30 * time.Second
+	}
+
+	c, err := net.ListenPacket("udp", ":0")
+	if err != nil {
+		return nil, listenErr(err)
+	}
+
+	ra, err := resolveUDPAddr("udp", addr)
+	if err != nil {
+		c.Close()
+		return nil, resolveErr(addr, err)
+	}
+
+	sender := &ResolvingSimpleSender{
+		c:        c,
+		addr:     addr,
+		ra:       ra,
+		interval: interval,
+		shutdown: make(chan bool),
+		done:     make(chan bool),
+	}
+
+	go sender.Start()
+	return sender, nil
+}
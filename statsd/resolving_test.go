@@ -0,0 +1,93 @@
+package statsd
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestResolvingSimpleSenderLiteralIP(t *testing.T) {
+	s, err := NewResolvingSimpleSender("127.0.0.1:8125", time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if _, ok := s.(*SimpleSender); !ok {
+		t.Fatalf("got %T expected *SimpleSender for a literal IP", s)
+	}
+}
+
+func TestResolvingSimpleSender(t *testing.T) {
+	l1, err := newUDPListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l1.Close()
+	l2, err := newUDPListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l2.Close()
+
+	var mx sync.Mutex
+	var resolveErr error
+	current := l1.LocalAddr().(*net.UDPAddr)
+	resolveUDPAddr = func(network, addr string) (*net.UDPAddr, error) {
+		mx.Lock()
+		defer mx.Unlock()
+		return current, resolveErr
+	}
+	defer func() { resolveUDPAddr = net.ResolveUDPAddr }()
+
+	s, err := NewResolvingSimpleSender("statsd.example.com:8125", time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// the address moves
+	mx.Lock()
+	current = l2.LocalAddr().(*net.UDPAddr)
+	mx.Unlock()
+	time.Sleep(20 * time.Millisecond)
+
+	// resolution fails, keeping the last good address
+	mx.Lock()
+	current, resolveErr = nil, errors.New("no such host")
+	mx.Unlock()
+	time.Sleep(20 * time.Millisecond)
+
+	_, err = s.Send([]byte("test.count:1|c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := make([]byte, 128)
+	n, _, err := l2.ReadFrom(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data[:n]) != "test.count:1|c" {
+		t.Fatalf("got '%s' expected 'test.count:1|c'", data[:n])
+	}
+}
+
+func TestResolvingSimpleSenderResolveError(t *testing.T) {
+	dnsErr := &net.DNSError{Err: "no such host", Name: "statsd.example.com", IsNotFound: true}
+	resolveUDPAddr = func(network, addr string) (*net.UDPAddr, error) {
+		return nil, dnsErr
+	}
+	defer func() { resolveUDPAddr = net.ResolveUDPAddr }()
+
+	_, err := NewResolvingSimpleSender("statsd.example.com:8125", time.Hour)
+	if !errors.Is(err, ErrResolveAddr) {
+		t.Fatalf("got error '%v' expected '%v'", err, ErrResolveAddr)
+	}
+	var target *net.DNSError
+	if !errors.As(err, &target) || target != dnsErr {
+		t.Fatalf("got error '%v' expected it to wrap '%v'", err, dnsErr)
+	}
+}
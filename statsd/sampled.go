@@ -0,0 +1,90 @@
+package statsd
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultEmitInterval is the longest SampledTiming goes without sending a
+// stat, unless set with WithSampledTimingInterval.
+const defaultEmitInterval = 10 * time.Second
+
+// emissions records when SampledTiming last sent each stat. Stats not sent
+// within the interval are pruned, as they are sent next as if never seen, so
+// that stat names of high cardinality do not grow it without bound.
+type emissions struct {
+	mx     sync.Mutex
+	last   map[string]time.Time
+	pruned time.Time
+}
+
+func newEmissions() *emissions {
+	return &emissions{last: make(map[string]time.Time)}
+}
+
+// record decides whether a metric for key is sent at now, given whether it
+// was sampled in. A metric that was sampled out is still sent, and forced is
+// true, if none has been sent for key within interval.
+func (e *emissions) record(key string, now time.Time, interval time.Duration, sampled bool) (send bool, forced bool) {
+	e.mx.Lock()
+	defer e.mx.Unlock()
+	if now.Sub(e.pruned) >= interval {
+		e.prune(now, interval)
+	}
+	last, seen := e.last[key]
+	forced = !sampled && (!seen || now.Sub(last) >= interval)
+	if sampled || forced {
+		e.last[key] = now
+	}
+	return sampled || forced, forced
+}
+
+// prune removes the stats not sent within interval of now. Must be called
+// with the mutex held.
+func (e *emissions) prune(now time.Time, interval time.Duration) {
+	for key, last := range e.last {
+		if now.Sub(last) >= interval {
+			delete(e.last, key)
+		}
+	}
+	e.pruned = now
+}
+
+// Submits a statsd timing type, sampled at rate, but sent at least once per
+// interval set with WithSampledTimingInterval (10 seconds by default), so
+// that stats with little traffic do not vanish under aggressive sampling.
+// Sampled in timings are sent with the rate, so that the server scales the
+// count. A timing sent only because the interval passed is sent without a
+// rate, counting as a single timing.
+// stat is a string name for the metric.
+// delta is the timing value as time.Duration
+// rate is the sample rate (0.0 to 1.0).
+func (s *Client) SampledTiming(stat string, delta time.Duration, rate float32) error {
+	var b [32]byte
+	value, ok := s.zeroTiming(s.appendDuration(b[:0], delta))
+	if !ok {
+		return nil
+	}
+	stat, rate, ok, err := s.check(stat, "|ms", rate)
+	if !ok {
+		return err
+	}
+
+	key := s.getPrefix() + s.separator + stat + s.tagString
+	send, forced := s.emissions.record(key, s.clock.Now(), s.emitInterval, s.sampled(stat, rate))
+	if !send {
+		atomic.AddUint64(&s.stats.sampledOut, 1)
+		return nil
+	}
+	if forced {
+		rate = 1
+	}
+
+	bp := bufPool.Get().(*[]byte)
+	buf := s.appendMetric((*bp)[:0], stat, value, "|ms", rate)
+	err = s.sendMetrics(buf, 1)
+	*bp = buf
+	bufPool.Put(bp)
+	return err
+}
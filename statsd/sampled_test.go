@@ -0,0 +1,112 @@
+package statsd
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestClientSampledTiming(t *testing.T) {
+	rs := NewRecordingSender()
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	c, err := NewClientWithOptions(
+		WithSender(rs),
+		WithPrefix("test"),
+		WithClock(clock),
+		WithSampledTimingInterval(time.Second),
+		// Float32() of this source is always 0.5
+		WithRandSource(constSource(1<<62)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	steps := []struct {
+		Advance  time.Duration
+		Rate     float32
+		Expected string
+	}{
+		// the first timing of a stat is always sent
+		{0, 0.1, "test.timing:1.00|ms"},
+		{100 * time.Millisecond, 0.1, ""},
+		{100 * time.Millisecond, 0.9, "test.timing:1.00|ms|@0.9"},
+		{500 * time.Millisecond, 0.1, ""},
+		{500 * time.Millisecond, 0.1, "test.timing:1.00|ms"},
+	}
+
+	for i, step := range steps {
+		rs.Clear()
+		clock.Advance(step.Advance)
+		err := c.SampledTiming("timing", time.Millisecond, step.Rate)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sent := rs.GetSent()
+		if step.Expected == "" {
+			if len(sent) != 0 {
+				t.Fatalf("step %d got '%s' expected nothing", i, sent)
+			}
+			continue
+		}
+		if len(sent) != 1 || string(sent[0]) != step.Expected {
+			t.Fatalf("step %d got '%s' expected '%s'", i, sent, step.Expected)
+		}
+	}
+
+	if n := c.Stats().SampledOut; n != 2 {
+		t.Fatalf("got %d sampled out expected 2", n)
+	}
+}
+
+func TestClientSampledTimingPerStat(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(
+		WithSender(rs),
+		WithRandSource(constSource(1<<62)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.SampledTiming("a", time.Millisecond, 0.1)
+	c.SampledTiming("b", time.Millisecond, 0.1)
+	c.NewSubStatter("sub").SampledTiming("a", time.Millisecond, 0.1)
+
+	if n := len(rs.GetSent()); n != 3 {
+		t.Fatalf("got %d sent expected 3, one per stat", n)
+	}
+}
+
+func TestClientSampledTimingPrune(t *testing.T) {
+	rs := NewRecordingSender()
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	c, err := NewClientWithOptions(
+		WithSender(rs),
+		WithClock(clock),
+		WithRandSource(constSource(1<<62)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for i := 0; i < 100; i++ {
+		c.SampledTiming("request."+strconv.Itoa(i), time.Millisecond, 0.1)
+	}
+	clock.Advance(defaultEmitInterval)
+	c.SampledTiming("other", time.Millisecond, 0.1)
+
+	e := c.(*Client).emissions
+	e.mx.Lock()
+	n := len(e.last)
+	e.mx.Unlock()
+	if n != 1 {
+		t.Fatalf("got %d stats recorded expected 1, the others pruned", n)
+	}
+	if sent := len(rs.GetSent()); sent != 101 {
+		t.Fatalf("got %d sent expected 101", sent)
+	}
+}
@@ -0,0 +1,30 @@
+package statsd
+
+// Sampler decides which metrics sent with a sample rate below 1 are sent,
+// set with WithSampler. It must be safe for concurrent use.
+type Sampler interface {
+	// ShouldSample reports whether a metric for stat, the name without the
+	// prefix of the client, is sent at rate, which is greater than 0 and
+	// less than 1.
+	ShouldSample(stat string, rate float32) (keep bool)
+}
+
+// HashSampler is a Sampler keeping a metric based on a hash of its stat
+// name, rather than randomly, so that the same stats are consistently kept
+// or dropped, by every process across a fleet. Of many stats at a rate, about
+// that fraction of them is kept. As each stat is either always or never sent,
+// counters and timings of the stats kept are still scaled by the server as
+// if sampled, so it suits choosing which of many similar stats to keep,
+// rather than estimating totals.
+type HashSampler struct{}
+
+// ShouldSample reports whether the FNV-1a hash of stat, as a fraction of
+// its range, is below rate.
+func (HashSampler) ShouldSample(stat string, rate float32) bool {
+	h := uint32(2166136261)
+	for i := 0; i < len(stat); i++ {
+		h ^= uint32(stat[i])
+		h *= 16777619
+	}
+	return float64(h)/(1<<32) < float64(rate)
+}
@@ -0,0 +1,61 @@
+package statsd
+
+import (
+	"strconv"
+	"testing"
+)
+
+// staticSampler samples in only the stats set to true.
+type staticSampler map[string]bool
+
+func (s staticSampler) ShouldSample(stat string, rate float32) bool { return s[stat] }
+
+func TestClientWithSampler(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithPrefix("test"),
+		WithSampler(staticSampler{"kept": true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.Inc("kept", 1, 0.5)
+	c.Inc("dropped", 1, 0.5)
+	// a rate of 1 is always sent, without consulting the sampler
+	c.Inc("dropped", 1, 1.0)
+
+	expected := []string{"test.kept:1|c|@0.5", "test.dropped:1|c"}
+	sent := rs.GetSent()
+	if len(sent) != len(expected) {
+		t.Fatalf("got '%s' expected '%s'", sent, expected)
+	}
+	for i, e := range expected {
+		if string(sent[i]) != e {
+			t.Fatalf("got '%s' expected '%s'", sent[i], e)
+		}
+	}
+	if n := c.Stats().SampledOut; n != 1 {
+		t.Fatalf("got %d sampled out expected 1", n)
+	}
+}
+
+func TestHashSampler(t *testing.T) {
+	var s HashSampler
+	kept := 0
+	for i := 0; i < 10000; i++ {
+		stat := "stat." + strconv.Itoa(i)
+		keep := s.ShouldSample(stat, 0.25)
+		for j := 0; j < 3; j++ {
+			if s.ShouldSample(stat, 0.25) != keep {
+				t.Fatalf("%s sampled inconsistently", stat)
+			}
+		}
+		if keep {
+			kept++
+		}
+	}
+	// about a quarter of the stats are kept
+	if kept < 2200 || kept > 2800 {
+		t.Fatalf("got %d of 10000 stats kept at 0.25 expected about 2500", kept)
+	}
+}
@@ -0,0 +1,330 @@
+package statsd
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BatchScope is a Statter that holds every metric until Flush or Close, such
+// as to send the metrics of a single request together. Unlike a
+// BufferedSender, nothing is sent on a timer. The metrics are sent in as few
+// packets as fit, each at most the maximum packet size of the client, so
+// that the metrics of a request smaller than a packet arrive together.
+//
+// Statters derived with WithTags, NewSubStatter and WithContext hold their
+// metrics in the same scope. A BatchScope is safe for concurrent use.
+type BatchScope struct {
+	client *Client
+	scope  *scope
+}
+
+// scope holds the metrics of a BatchScope and the Statters derived from it.
+type scope struct {
+	mx      sync.Mutex
+	buf     []byte
+	ends    []int
+	maxSize int
+	closed  bool
+}
+
+// BeginBatch returns a BatchScope holding metrics from this client until
+// flushed.
+func (s *Client) BeginBatch() *BatchScope {
+	return &BatchScope{client: s, scope: &scope{maxSize: s.NewBatch().maxSize}}
+}
+
+// add formats metrics with f onto the scope, as a Batch would.
+func (s *BatchScope) add(f func(b *Batch) error) error {
+	s.scope.mx.Lock()
+	defer s.scope.mx.Unlock()
+	if s.scope.closed {
+		return ErrClosed
+	}
+	b := &Batch{client: s.client, buf: s.scope.buf, n: uint64(len(s.scope.ends))}
+	err := f(b)
+	if b.n > uint64(len(s.scope.ends)) {
+		s.scope.ends = append(s.scope.ends, len(b.buf))
+	}
+	s.scope.buf = b.buf
+	return err
+}
+
+// Inc adds to the scope as for Batch.Inc.
+func (s *BatchScope) Inc(stat string, value int64, rate float32) error {
+	return s.add(func(b *Batch) error { return b.Inc(stat, value, rate) })
+}
+
+// Dec adds to the scope as for Batch.Dec.
+func (s *BatchScope) Dec(stat string, value int64, rate float32) error {
+	return s.add(func(b *Batch) error { return b.Dec(stat, value, rate) })
+}
+
+// Gauge adds to the scope as for Batch.Gauge.
+func (s *BatchScope) Gauge(stat string, value int64, rate float32) error {
+	return s.add(func(b *Batch) error { return b.Gauge(stat, value, rate) })
+}
+
+// GaugeDelta adds to the scope as for Batch.GaugeDelta.
+func (s *BatchScope) GaugeDelta(stat string, value int64, rate float32) error {
+	return s.add(func(b *Batch) error { return b.GaugeDelta(stat, value, rate) })
+}
+
+// GaugeFloat adds to the scope as for Batch.GaugeFloat.
+func (s *BatchScope) GaugeFloat(stat string, value float64, rate float32) error {
+	return s.add(func(b *Batch) error { return b.GaugeFloat(stat, value, rate) })
+}
+
+// GaugeUint64 adds to the scope as for Batch.GaugeUint64.
+func (s *BatchScope) GaugeUint64(stat string, value uint64, rate float32) error {
+	return s.add(func(b *Batch) error { return b.GaugeUint64(stat, value, rate) })
+}
+
+// GaugeTime adds to the scope as for Batch.GaugeTime.
+func (s *BatchScope) GaugeTime(stat string, t time.Time, rate float32) error {
+	return s.add(func(b *Batch) error { return b.GaugeTime(stat, t, rate) })
+}
+
+// GaugeTimeMillis adds to the scope as for Batch.GaugeTimeMillis.
+func (s *BatchScope) GaugeTimeMillis(stat string, t time.Time, rate float32) error {
+	return s.add(func(b *Batch) error { return b.GaugeTimeMillis(stat, t, rate) })
+}
+
+// RateGauge adds to the scope as for Batch.RateGauge.
+func (s *BatchScope) RateGauge(stat string, count int64, window time.Duration, rate float32) error {
+	return s.add(func(b *Batch) error { return b.RateGauge(stat, count, window, rate) })
+}
+
+// GaugeDeltaFloat adds to the scope as for Batch.GaugeDeltaFloat.
+func (s *BatchScope) GaugeDeltaFloat(stat string, value float64, rate float32) error {
+	return s.add(func(b *Batch) error { return b.GaugeDeltaFloat(stat, value, rate) })
+}
+
+// Timing adds to the scope as for Batch.Timing.
+func (s *BatchScope) Timing(stat string, delta int64, rate float32) error {
+	return s.add(func(b *Batch) error { return b.Timing(stat, delta, rate) })
+}
+
+// TimingDuration adds to the scope as for Batch.TimingDuration.
+func (s *BatchScope) TimingDuration(stat string, delta time.Duration, rate float32) error {
+	return s.add(func(b *Batch) error { return b.TimingDuration(stat, delta, rate) })
+}
+
+// TimingGauge adds to the scope as for Batch.TimingGauge.
+func (s *BatchScope) TimingGauge(stat string, delta time.Duration, rate float32) error {
+	return s.add(func(b *Batch) error { return b.TimingGauge(stat, delta, rate) })
+}
+
+// Set adds to the scope as for Batch.Set.
+func (s *BatchScope) Set(stat string, value string, rate float32) error {
+	return s.add(func(b *Batch) error { return b.Set(stat, value, rate) })
+}
+
+// Histogram adds to the scope as for Batch.Histogram.
+func (s *BatchScope) Histogram(stat string, value int64, rate float32) error {
+	return s.add(func(b *Batch) error { return b.Histogram(stat, value, rate) })
+}
+
+// HistogramFloat adds to the scope as for Batch.HistogramFloat.
+func (s *BatchScope) HistogramFloat(stat string, value float64, rate float32) error {
+	return s.add(func(b *Batch) error { return b.HistogramFloat(stat, value, rate) })
+}
+
+// Distribution adds to the scope as for Batch.Distribution.
+func (s *BatchScope) Distribution(stat string, value float64, rate float32) error {
+	return s.add(func(b *Batch) error { return b.Distribution(stat, value, rate) })
+}
+
+// Meter adds to the scope as for Batch.Meter.
+func (s *BatchScope) Meter(stat string, value int64, rate float32) error {
+	return s.add(func(b *Batch) error { return b.Meter(stat, value, rate) })
+}
+
+// Custom adds to the scope as for Batch.Custom.
+func (s *BatchScope) Custom(stat string, value string, suffix string, rate float32) error {
+	return s.add(func(b *Batch) error { return b.Custom(stat, value, suffix, rate) })
+}
+
+// Raw adds to the scope as for Batch.Raw.
+func (s *BatchScope) Raw(stat string, value string, rate float32) error {
+	return s.add(func(b *Batch) error { return b.Raw(stat, value, rate) })
+}
+
+// IncMany adds a count for each stat to the scope, in order of the names.
+func (s *BatchScope) IncMany(counts map[string]int64, rate float32) error {
+	stats := make([]string, 0, len(counts))
+	for stat := range counts {
+		stats = append(stats, stat)
+	}
+	sort.Strings(stats)
+	return s.add(func(b *Batch) error {
+		var err error
+		for _, stat := range stats {
+			err = errors.Join(err, b.Inc(stat, counts[stat], rate))
+		}
+		return err
+	})
+}
+
+// TimingMulti adds each timing to the scope, as for Batch.TimingDuration.
+func (s *BatchScope) TimingMulti(stat string, deltas []time.Duration, rate float32) error {
+	var err error
+	for _, d := range deltas {
+		err = errors.Join(err, s.TimingDuration(stat, d, rate))
+	}
+	return err
+}
+
+// SampledTiming is sent by the client immediately, as whether it is sent
+// depends on when the stat was last sent.
+func (s *BatchScope) SampledTiming(stat string, delta time.Duration, rate float32) error {
+	return s.client.SampledTiming(stat, delta, rate)
+}
+
+// RawBytes adds to the scope as for Batch.Raw.
+func (s *BatchScope) RawBytes(stat []byte, value []byte, rate float32) error {
+	return s.Raw(string(stat), string(value), rate)
+}
+
+// ServiceCheck is sent by the client immediately.
+func (s *BatchScope) ServiceCheck(name string, status int, tags ...Tag) error {
+	return s.client.ServiceCheck(name, status, tags...)
+}
+
+// Event is sent by the client immediately.
+func (s *BatchScope) Event(title, text string, tags ...Tag) error {
+	return s.client.Event(title, text, tags...)
+}
+
+// NewTiming returns a Timing that adds to the scope.
+func (s *BatchScope) NewTiming() Timing {
+	if s.client == nil || s.client.clock == nil {
+		return newTiming(s, realClock{})
+	}
+	return newTiming(s, s.client.clock)
+}
+
+// Counter returns a Counter that adds to the scope.
+func (s *BatchScope) Counter(stat string, rate float32) Counter {
+	return NewCounter(s, stat, rate)
+}
+
+// BoundGauge returns a Gauge that adds to the scope.
+func (s *BatchScope) BoundGauge(stat string, rate float32) Gauge {
+	return NewGauge(s, stat, rate)
+}
+
+// Timer returns a Timer that adds to the scope.
+func (s *BatchScope) Timer(stat string, rate float32) Timer {
+	return NewTimer(s, stat, rate)
+}
+
+// Calls f, and adds its duration as a timing to the scope.
+func (s *BatchScope) Time(stat string, rate float32, f func()) error {
+	t := s.NewTiming()
+	f()
+	return t.Send(stat, rate)
+}
+
+// Returns a function that, if the goroutine is panicking, adds a count of 1
+// for stat to the scope, flushes it, and panics again.
+func (s *BatchScope) Recover(stat string) func() {
+	return func() {
+		if r := recover(); r != nil {
+			s.Inc(stat, 1, 1.0)
+			s.Flush()
+			panic(r)
+		}
+	}
+}
+
+// NewBatch returns a Batch of the client, which is sent by its own Submit.
+func (s *BatchScope) NewBatch() *Batch {
+	return s.client.NewBatch()
+}
+
+// SetPrefix sets the prefix of the client, as for Client.SetPrefix.
+func (s *BatchScope) SetPrefix(prefix string) {
+	s.client.SetPrefix(prefix)
+}
+
+// WithTags returns a BatchScope adding to the same scope with the tags
+// added, as for Client.WithTags.
+func (s *BatchScope) WithTags(tags ...Tag) Statter {
+	return &BatchScope{client: s.client.WithTags(tags...).(*Client), scope: s.scope}
+}
+
+// NewSubStatter returns a BatchScope adding to the same scope with prefix
+// appended, as for Client.NewSubStatter.
+func (s *BatchScope) NewSubStatter(prefix string) Statter {
+	return &BatchScope{client: s.client.NewSubStatter(prefix).(*Client), scope: s.scope}
+}
+
+// WithContext returns a BatchScope adding to the same scope, whose metrics
+// are not added once ctx is done, as for Client.WithContext.
+func (s *BatchScope) WithContext(ctx context.Context) Statter {
+	return &BatchScope{client: s.client.WithContext(ctx).(*Client), scope: s.scope}
+}
+
+// Flush sends the metrics held by the scope, and empties it. Metrics are
+// split into packets of at most the maximum packet size, between metrics.
+func (s *BatchScope) Flush() error {
+	s.scope.mx.Lock()
+	defer s.scope.mx.Unlock()
+	return s.flush()
+}
+
+// flush is as Flush, with the mutex held.
+func (s *BatchScope) flush() error {
+	sc := s.scope
+	if s.client == nil || len(sc.ends) == 0 {
+		return nil
+	}
+
+	var err error
+	start, n := 0, uint64(0)
+	for i, end := range sc.ends {
+		n++
+		if i+1 < len(sc.ends) && sc.ends[i+1]-start <= sc.maxSize {
+			continue
+		}
+		err = errors.Join(err, s.client.sendMetrics(sc.buf[start:end], n))
+		// skip the newline separating the next metric
+		start, n = end+1, 0
+	}
+	sc.buf = sc.buf[:0]
+	sc.ends = sc.ends[:0]
+	return err
+}
+
+// Enabled reports whether the client is enabled and the scope not closed.
+func (s *BatchScope) Enabled() bool {
+	s.scope.mx.Lock()
+	defer s.scope.mx.Unlock()
+	return !s.scope.closed && s.client.Enabled()
+}
+
+// Stats returns the statistics of the client.
+func (s *BatchScope) Stats() ClientStats {
+	return s.client.Stats()
+}
+
+// PublishExpvar publishes the statistics of the client, as for
+// Client.PublishExpvar.
+func (s *BatchScope) PublishExpvar(name string) {
+	s.client.PublishExpvar(name)
+}
+
+// Close flushes the scope, after which metrics are not added and return
+// ErrClosed. The client is not closed. Later calls return nil.
+func (s *BatchScope) Close() error {
+	s.scope.mx.Lock()
+	defer s.scope.mx.Unlock()
+	if s.scope.closed {
+		return nil
+	}
+	s.scope.closed = true
+	return s.flush()
+}
@@ -0,0 +1,119 @@
+package statsd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBatchScope(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithPrefix("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	s := c.(*Client).BeginBatch()
+	s.Inc("count", 1, 1.0)
+	s.Gauge("gauge", -2, 1.0)
+	s.WithTags(Tag{"env", "prod"}).Inc("tagged", 1, 1.0)
+	s.NewSubStatter("sub").Timing("timing", 3, 1.0)
+	if sent := rs.GetSent(); len(sent) != 0 {
+		t.Fatalf("got '%s' expected nothing sent before Flush", sent)
+	}
+
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	expected := "test.count:1|c\ntest.gauge:0|g\ntest.gauge:-2|g\ntest.tagged:1|c|#env:prod\ntest.sub.timing:3|ms"
+	sent := rs.GetSent()
+	if len(sent) != 1 || string(sent[0]) != expected {
+		t.Fatalf("got '%s' expected '%s'", sent, expected)
+	}
+
+	rs.Clear()
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if sent := rs.GetSent(); len(sent) != 0 {
+		t.Fatalf("got '%s' expected nothing sent for an empty scope", sent)
+	}
+
+	s.Inc("count", 2, 1.0)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	sent = rs.GetSent()
+	if len(sent) != 1 || string(sent[0]) != "test.count:2|c" {
+		t.Fatalf("got '%s' expected 'test.count:2|c'", sent)
+	}
+	if err := s.Inc("count", 1, 1.0); !errors.Is(err, ErrClosed) {
+		t.Fatalf("got error '%v' expected '%v'", err, ErrClosed)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("second Close got error '%v' expected nil", err)
+	}
+}
+
+func TestBatchScopeSplit(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithMaxPacketSize(64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	s := c.(*Client).BeginBatch()
+	for i := 0; i < 10; i++ {
+		s.Inc("count.xxxxxxxxxx", 1, 1.0)
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := 0
+	sent := rs.GetSent()
+	if len(sent) < 2 {
+		t.Fatalf("got %d packets expected the scope split", len(sent))
+	}
+	for _, p := range sent {
+		if len(p) > 64 {
+			t.Fatalf("got packet of %d bytes expected at most 64", len(p))
+		}
+		for _, line := range strings.Split(string(p), "\n") {
+			if line != "count.xxxxxxxxxx:1|c" {
+				t.Fatalf("got line '%s' expected 'count.xxxxxxxxxx:1|c'", line)
+			}
+			lines++
+		}
+	}
+	if lines != 10 {
+		t.Fatalf("got %d metrics expected 10", lines)
+	}
+	if n := c.Stats().Sent; n != 10 {
+		t.Fatalf("got %d metrics counted expected 10", n)
+	}
+}
+
+func TestBatchScopeSplitNewline(t *testing.T) {
+	rs := NewRecordingSender()
+	// exactly fits two metrics, without the terminating newline
+	c, err := NewClientWithOptions(WithSender(rs), WithMaxPacketSize(11), WithNewlineTerminator(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	s := c.(*Client).BeginBatch()
+	s.Inc("a", 1, 1.0)
+	s.Inc("a", 1, 1.0)
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := rs.GetSent()
+	if len(sent) != 2 || string(sent[0]) != "a:1|c\n" || string(sent[1]) != "a:1|c\n" {
+		t.Fatalf("got '%q' expected two newline terminated packets", sent)
+	}
+}
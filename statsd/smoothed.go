@@ -0,0 +1,56 @@
+package statsd
+
+import (
+	"fmt"
+	"sync"
+)
+
+// SmoothedGauge sends a gauge smoothed with an exponentially weighted moving
+// average, for noisy sources such as CPU usage or queue depth. It is safe for
+// concurrent use.
+type SmoothedGauge struct {
+	statter Statter
+	stat    string
+	alpha   float64
+	rate    float32
+
+	mx    sync.Mutex
+	value float64
+	init  bool
+}
+
+// Update adds value to the average, and sends the smoothed value as a
+// gauge. The first value is sent as is. A NaN or infinite value returns
+// ErrInvalidValue, and leaves the average unchanged.
+func (g *SmoothedGauge) Update(value float64) error {
+	if err := checkFloat(value); err != nil {
+		return err
+	}
+	g.mx.Lock()
+	defer g.mx.Unlock()
+	if g.init {
+		g.value = g.alpha*value + (1-g.alpha)*g.value
+	} else {
+		g.value = value
+		g.init = true
+	}
+	// sent with the lock held, so that gauges are sent in the order updated
+	return g.statter.GaugeFloat(g.stat, g.value, g.rate)
+}
+
+// Value returns the smoothed value, or 0 before the first Update.
+func (g *SmoothedGauge) Value() float64 {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+	return g.value
+}
+
+// NewSmoothedGauge returns a SmoothedGauge sending stat via statter at rate.
+// alpha is the weight of each new value, greater than 0 and at most 1,
+// where smaller values smooth more and 1 sends every value as is.
+func NewSmoothedGauge(statter Statter, stat string, alpha float64, rate float32) (*SmoothedGauge, error) {
+	if !(alpha > 0 && alpha <= 1) {
+		return nil, fmt.Errorf("Smoothing factor %v is not greater than 0 and at most 1", alpha)
+	}
+	return &SmoothedGauge{statter: statter, stat: stat, alpha: alpha, rate: rate}, nil
+}
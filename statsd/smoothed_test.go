@@ -0,0 +1,69 @@
+package statsd
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSmoothedGauge(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithPrefix("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	g, err := NewSmoothedGauge(c, "cpu", 0.5, 1.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []float64{10, 20, 20} {
+		if err := g.Update(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if v := g.Value(); v != 17.5 {
+		t.Fatalf("got value %v expected 17.5", v)
+	}
+
+	expected := []string{"test.cpu:10|g", "test.cpu:15|g", "test.cpu:17.5|g"}
+	sent := rs.GetSent()
+	if len(sent) != len(expected) {
+		t.Fatalf("got '%s' expected '%s'", sent, expected)
+	}
+	for i, e := range expected {
+		if string(sent[i]) != e {
+			t.Fatalf("got '%s' expected '%s'", sent[i], e)
+		}
+	}
+}
+
+func TestSmoothedGaugeConcurrent(t *testing.T) {
+	g, err := NewSmoothedGauge(&NoopClient{}, "cpu", 0.1, 1.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				g.Update(5)
+			}
+		}()
+	}
+	wg.Wait()
+	if v := g.Value(); v != 5 {
+		t.Fatalf("got value %v expected 5", v)
+	}
+}
+
+func TestSmoothedGaugeAlpha(t *testing.T) {
+	for _, alpha := range []float64{0, -0.5, 1.5} {
+		if _, err := NewSmoothedGauge(&NoopClient{}, "cpu", alpha, 1.0); err == nil {
+			t.Fatalf("expected an error for alpha %v", alpha)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package statsd
+
+import (
+	"bytes"
+	"errors"
+	"net"
+)
+
+// SplittingSender provides a send interface that splits newline separated
+// data larger than a maximum packet size, such as from a Batch, into several
+// packets at line boundaries.
+type SplittingSender struct {
+	sender Sender
+	mtu    int
+}
+
+// Send sends the data to the underlying sender, split into packets of at
+// most mtu bytes. A single line longer than mtu is still sent, in a packet of
+// its own, and any send errors are joined together.
+func (s *SplittingSender) Send(data []byte) (int, error) {
+	if len(data) <= s.mtu {
+		return s.sender.Send(data)
+	}
+
+	var errs []error
+	total := 0
+	for len(data) > 0 {
+		if len(data) <= s.mtu {
+			n, err := s.sender.Send(data)
+			total += n
+			if err != nil {
+				errs = append(errs, err)
+			}
+			break
+		}
+
+		// the newline ending a chunk of up to mtu bytes is not sent
+		end := bytes.LastIndexByte(data[:s.mtu+1], '\n')
+		if end <= 0 {
+			// a single line longer than mtu
+			end = bytes.IndexByte(data, '\n')
+			if end < 0 {
+				end = len(data)
+			}
+		}
+
+		if end > 0 {
+			n, err := s.sender.Send(data[:end])
+			total += n
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if end == len(data) {
+			break
+		}
+		data = data[end+1:]
+	}
+	return total, errors.Join(errs...)
+}
+
+// Flush flushes the underlying sender, if it buffers data.
+func (s *SplittingSender) Flush() error {
+	if f, ok := s.sender.(flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// RemoteAddr returns the address the underlying sender sends to.
+func (s *SplittingSender) RemoteAddr() net.Addr {
+	if a, ok := s.sender.(Addressable); ok {
+		return a.RemoteAddr()
+	}
+	return nil
+}
+
+// Reconnect reconnects the underlying sender, if it implements Reconnecter.
+func (s *SplittingSender) Reconnect() error {
+	if r, ok := s.sender.(Reconnecter); ok {
+		return r.Reconnect()
+	}
+	return nil
+}
+
+// Ping pings the underlying sender, if it implements Pinger.
+func (s *SplittingSender) Ping() error {
+	if p, ok := s.sender.(Pinger); ok {
+		return p.Ping()
+	}
+	return errNoPinger
+}
+
+// Dropped returns the number of sends dropped by the underlying sender.
+func (s *SplittingSender) Dropped() uint64 {
+	if dc, ok := s.sender.(dropCounter); ok {
+		return dc.Dropped()
+	}
+	return 0
+}
+
+// Closes the underlying sender.
+func (s *SplittingSender) Close() error {
+	return s.sender.Close()
+}
+
+// Returns a new SplittingSender, sending packets of at most mtu bytes via
+// sender.
+//
+// If mtu is 0, defaults to 1432 bytes.
+func NewSplittingSender(sender Sender, mtu int) Sender {
+	if mtu <= 0 {
+		mtu = defaultMaxPacketSize
+	}
+	return &SplittingSender{sender: sender, mtu: mtu}
+}
@@ -0,0 +1,94 @@
+package statsd
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var splittingSenderTests = []struct {
+	Data     string
+	Expected []string
+}{
+	{"a:1|c\nb:1|c", []string{"a:1|c\nb:1|c"}},
+	{"a:1|c\nb:1|c\nc:1|c\n", []string{"a:1|c\nb:1|c", "c:1|c\n"}},
+	{"aaaaaaaaaaaaaa:1|c\nb:1|c", []string{"aaaaaaaaaaaaaa:1|c", "b:1|c"}},
+	{"a:1|c\naaaaaaaaaaaaaa:1|c\nb:1|c\n", []string{"a:1|c", "aaaaaaaaaaaaaa:1|c", "b:1|c\n"}},
+	{"aaaaaaaaaaaaaa:1|c", []string{"aaaaaaaaaaaaaa:1|c"}},
+}
+
+func TestSplittingSender(t *testing.T) {
+	for _, tt := range splittingSenderTests {
+		rs := NewRecordingSender()
+		s := NewSplittingSender(rs, 12)
+
+		_, err := s.Send([]byte(tt.Data))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sent := rs.GetSent()
+		if len(sent) != len(tt.Expected) {
+			t.Fatalf("%q got %q expected %q", tt.Data, sent, tt.Expected)
+		}
+		for i, e := range tt.Expected {
+			if string(sent[i]) != e {
+				t.Fatalf("%q got %q expected %q", tt.Data, sent, tt.Expected)
+			}
+		}
+	}
+}
+
+func TestSplittingSenderError(t *testing.T) {
+	sendErr := errors.New("message too long")
+	s := NewSplittingSender(errorSender{sendErr}, 12)
+
+	_, err := s.Send([]byte("aaaaaaaaaaaaaa:1|c\nb:1|c"))
+	if !errors.Is(err, sendErr) {
+		t.Fatalf("got error '%v' expected '%v'", err, sendErr)
+	}
+}
+
+func TestSplittingSenderForwards(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(NewSplittingSender(newBufferedSender(rs, time.Hour, 1432, 0), 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.Inc("count", 1, 1.0)
+	if err := c.(*Client).Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if sent := rs.GetSent(); len(sent) != 1 || string(sent[0]) != "count:1|c\n" {
+		t.Fatalf("got '%s' expected the buffered sender flushed", sent)
+	}
+
+	cs := &connSender{RecordingSender: NewRecordingSender()}
+	s := NewSplittingSender(cs, 0).(*SplittingSender)
+	if err := s.Reconnect(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	if cs.reconnects != 1 || cs.pings != 1 {
+		t.Fatalf("got %d reconnects %d pings expected 1 each", cs.reconnects, cs.pings)
+	}
+	if a := s.RemoteAddr(); a == nil || a.String() != "127.0.0.1:8125" {
+		t.Fatalf("got %v expected the remote address of the underlying sender", a)
+	}
+
+	bs := &blockingSender{unblock: make(chan bool)}
+	as := NewAsyncSender(bs, 1)
+	defer as.Close()
+	defer close(bs.unblock)
+	s = NewSplittingSender(as, 0).(*SplittingSender)
+	for i := 0; i < 5; i++ {
+		s.Send([]byte("count:1|c"))
+	}
+	if n := s.Dropped(); n == 0 {
+		t.Fatal("expected the drops of the underlying sender")
+	}
+}
@@ -0,0 +1,82 @@
+package statsd
+
+import (
+	"expvar"
+	"sync"
+	"sync/atomic"
+)
+
+// ClientStats are counts of what a Client has done with the metrics
+// submitted to it. Clients derived with WithTags, NewSubStatter or
+// WithContext share the counts of their parent.
+type ClientStats struct {
+	// Sent is the number of metrics handed to the sender without error.
+	Sent uint64
+	// BytesSent is the size of the metrics counted in Sent.
+	BytesSent uint64
+	// SampledOut is the number of metrics skipped due to sampling.
+	SampledOut uint64
+	// Errors is the number of metrics for which the sender returned an error,
+	// or which were not sent as larger than the size set with
+	// WithMaxPacketSize.
+	Errors uint64
+	// Dropped is the number of metrics discarded by the sender itself, such
+	// as when the queue of an AsyncSender is full or a rate limit is reached.
+	// It is only reported for senders that track it.
+	Dropped uint64
+}
+
+// clientStats holds the counters behind ClientStats.
+type clientStats struct {
+	sent       uint64
+	bytesSent  uint64
+	sampledOut uint64
+	errors     uint64
+}
+
+// dropCounter is implemented by Senders that count the data they discard,
+// such as AsyncSender.
+type dropCounter interface {
+	Dropped() uint64
+}
+
+// Stats returns the counts of metrics sent, sampled out, and lost.
+func (s *Client) Stats() ClientStats {
+	if s == nil {
+		return ClientStats{}
+	}
+	cs := ClientStats{
+		Sent:       atomic.LoadUint64(&s.stats.sent),
+		BytesSent:  atomic.LoadUint64(&s.stats.bytesSent),
+		SampledOut: atomic.LoadUint64(&s.stats.sampledOut),
+		Errors:     atomic.LoadUint64(&s.stats.errors),
+	}
+	if dc, ok := s.sender.(dropCounter); ok {
+		cs.Dropped = dc.Dropped()
+	}
+	return cs
+}
+
+// PublishExpvar publishes the client Stats as an expvar with the given name,
+// so they are served with the other expvars at /debug/vars. Unlike
+// expvar.Publish, a name already published does nothing, keeping the
+// existing expvar.
+func (s *Client) PublishExpvar(name string) {
+	publishExpvar(name, func() interface{} {
+		return s.Stats()
+	})
+}
+
+// expvarMx serializes checking and publishing expvar names.
+var expvarMx sync.Mutex
+
+// publishExpvar publishes f as an expvar with the given name, unless the name
+// is already published.
+func publishExpvar(name string, f expvar.Func) {
+	expvarMx.Lock()
+	defer expvarMx.Unlock()
+	if expvar.Get(name) != nil {
+		return
+	}
+	expvar.Publish(name, f)
+}
@@ -0,0 +1,116 @@
+package statsd
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"testing"
+)
+
+func TestClientStats(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(
+		WithSender(rs),
+		// Float32() of this source is always 0.5
+		WithRandSource(constSource(1<<62)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.Inc("count", 1, 1.0)
+	c.WithTags(Tag{"a", "1"}).Inc("count", 1, 1.0)
+	c.Inc("count", 1, 0.5)
+
+	expected := ClientStats{Sent: 2, BytesSent: 23, SampledOut: 1}
+	if stats := c.Stats(); stats != expected {
+		t.Fatalf("got %+v expected %+v", stats, expected)
+	}
+}
+
+func TestClientOnError(t *testing.T) {
+	sendErr := errors.New("send failed")
+	var c Statter
+	var handled []error
+	c, err := NewClientWithOptions(
+		WithSender(errorSender{sendErr}),
+		WithOnError(func(err error) {
+			handled = append(handled, err)
+			// the callback may re-enter the client
+			c.Stats()
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Inc("count", 1, 1.0)
+	if err != sendErr {
+		t.Fatalf("got error '%v' expected '%v'", err, sendErr)
+	}
+	if len(handled) != 1 || handled[0] != sendErr {
+		t.Fatalf("got handled errors %v expected [%v]", handled, sendErr)
+	}
+
+	expected := ClientStats{Errors: 1}
+	if stats := c.Stats(); stats != expected {
+		t.Fatalf("got %+v expected %+v", stats, expected)
+	}
+}
+
+func TestClientStatsDropped(t *testing.T) {
+	bs := &blockingSender{unblock: make(chan bool)}
+	c, err := NewClientWithOptions(WithSender(NewAsyncSender(bs, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		c.Inc("count", 1, 1.0)
+	}
+	if stats := c.Stats(); stats.Dropped < 8 {
+		t.Fatalf("got %d dropped expected at least 8", stats.Dropped)
+	}
+
+	close(bs.unblock)
+	c.Close()
+}
+
+// expvarRuns counts the runs of TestClientPublishExpvar, as with -count.
+var expvarRuns int
+
+func TestClientPublishExpvar(t *testing.T) {
+	c, err := NewClientWithOptions(WithSender(NewRecordingSender()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// unique per run, as expvars can't be unpublished
+	expvarRuns++
+	name := fmt.Sprintf("statsd_test_client_%d", expvarRuns)
+	c.PublishExpvar(name)
+	c.Inc("count", 1, 1.0)
+
+	// publishing the name again keeps the first expvar
+	other, err := NewClientWithOptions(WithSender(NewRecordingSender()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.PublishExpvar(name)
+	(&NoopClient{}).PublishExpvar(name)
+
+	v := expvar.Get(name)
+	if v == nil {
+		t.Fatal("expvar not published")
+	}
+	var stats ClientStats
+	err = json.Unmarshal([]byte(v.String()), &stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := ClientStats{Sent: 1, BytesSent: 9}
+	if stats != expected {
+		t.Fatalf("got %+v expected %+v", stats, expected)
+	}
+}
@@ -0,0 +1,98 @@
+package statsdhttp
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cactus/go-statsd-client/statsd"
+)
+
+var middlewareTests = []struct {
+	Status   int
+	Expected string
+}{
+	{0, "http.2xx:1|c"},
+	{http.StatusOK, "http.2xx:1|c"},
+	{http.StatusNotFound, "http.4xx:1|c"},
+	{http.StatusInternalServerError, "http.5xx:1|c"},
+}
+
+func TestMiddleware(t *testing.T) {
+	for _, tt := range middlewareTests {
+		rs := statsd.NewRecordingSender()
+		c, err := statsd.NewClientWithOptions(statsd.WithSender(rs))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		status := tt.Status
+		h := Middleware(c, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if status != 0 {
+				w.WriteHeader(status)
+			}
+			w.Write([]byte("ok"))
+		}), "http")
+
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+		sent := rs.GetSent()
+		if len(sent) != 2 {
+			t.Fatalf("got %d payloads expected 2", len(sent))
+		}
+		if !strings.HasPrefix(string(sent[0]), "http.duration:") || !strings.HasSuffix(string(sent[0]), "|ms") {
+			t.Fatalf("got '%s' expected a timing", sent[0])
+		}
+		if string(sent[1]) != tt.Expected {
+			t.Fatalf("got '%s' expected '%s'", sent[1], tt.Expected)
+		}
+	}
+}
+
+// hijackRecorder is a ResponseRecorder implementing http.Hijacker.
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (r *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	r.hijacked = true
+	return nil, nil, nil
+}
+
+func TestMiddlewareFlushHijack(t *testing.T) {
+	rs := statsd.NewRecordingSender()
+	c, err := statsd.NewClientWithOptions(statsd.WithSender(rs))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var flushErr, hijackErr, controllerErr error
+	h := Middleware(c, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		_, _, hijackErr = w.(http.Hijacker).Hijack()
+		flushErr = http.NewResponseController(w).Flush()
+		_, _, controllerErr = http.NewResponseController(w).Hijack()
+	}), "http")
+
+	w := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if !w.Flushed || !w.hijacked {
+		t.Fatalf("got flushed %v hijacked %v expected both", w.Flushed, w.hijacked)
+	}
+	if hijackErr != nil || flushErr != nil || controllerErr != nil {
+		t.Fatal(hijackErr, flushErr, controllerErr)
+	}
+
+	// a writer that can't be hijacked returns an error
+	h = Middleware(c, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _, hijackErr = w.(http.Hijacker).Hijack()
+	}), "http")
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if hijackErr == nil {
+		t.Fatal("expected an error hijacking a writer without http.Hijacker")
+	}
+}
@@ -0,0 +1,195 @@
+/*
+Package statsdtest provides a Statter for asserting the metrics sent by
+instrumented code in tests, as structured records rather than wire strings.
+*/
+package statsdtest
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/cactus/go-statsd-client/statsd"
+)
+
+// Metric is a single metric, parsed from a line sent by the client.
+type Metric struct {
+	// Name is the full stat name, including any prefix.
+	Name string
+	// Value is the value as sent, such as "+5" for a gauge delta.
+	Value string
+	// Type is the metric type, such as "c", "g" or "ms".
+	Type string
+	// Rate is the sample rate, 1 when none was sent.
+	Rate float32
+	// Tags are the DogStatsD or InfluxDB tags, in the order sent.
+	Tags []statsd.Tag
+}
+
+// Statter is a statsd.Client recording everything it sends, with helpers
+// to find and assert the metrics sent. Events and service checks are
+// recorded by the client, but are not parsed into metrics.
+type Statter struct {
+	statsd.Statter
+	rs *statsd.RecordingSender
+}
+
+// New returns a Statter configured with opts, which must not include
+// statsd.WithSender or statsd.WithAddr.
+func New(opts ...statsd.Option) (*Statter, error) {
+	rs := statsd.NewRecordingSender()
+	c, err := statsd.NewClientWithOptions(append(opts, statsd.WithSender(rs))...)
+	if err != nil {
+		return nil, err
+	}
+	return &Statter{Statter: c, rs: rs}, nil
+}
+
+// Metrics returns every metric sent so far, in order.
+func (s *Statter) Metrics() []Metric {
+	var metrics []Metric
+	for _, p := range s.rs.GetSent() {
+		for _, line := range strings.Split(string(p), "\n") {
+			metrics = append(metrics, parseLine(line)...)
+		}
+	}
+	return metrics
+}
+
+// Find returns the metrics sent so far with the stat name.
+func (s *Statter) Find(name string) []Metric {
+	var found []Metric
+	for _, m := range s.Metrics() {
+		if m.Name == name {
+			found = append(found, m)
+		}
+	}
+	return found
+}
+
+// Count returns the sum of the counters sent with the stat name, without
+// scaling by the sample rate.
+func (s *Statter) Count(name string) int64 {
+	var n int64
+	for _, m := range s.Find(name) {
+		if m.Type != "c" {
+			continue
+		}
+		v, _ := strconv.ParseInt(m.Value, 10, 64)
+		n += v
+	}
+	return n
+}
+
+// GaugeValue returns the value of the gauge with the stat name, applying
+// deltas as a statsd server would, and whether it was sent at all.
+func (s *Statter) GaugeValue(name string) (float64, bool) {
+	var value float64
+	found := false
+	for _, m := range s.Find(name) {
+		if m.Type != "g" {
+			continue
+		}
+		v, err := strconv.ParseFloat(m.Value, 64)
+		if err != nil {
+			continue
+		}
+		if strings.HasPrefix(m.Value, "+") || strings.HasPrefix(m.Value, "-") {
+			value += v
+		} else {
+			value = v
+		}
+		found = true
+	}
+	return value, found
+}
+
+// Reset discards the metrics sent so far.
+func (s *Statter) Reset() {
+	s.rs.Clear()
+}
+
+// AssertCounter fails the test unless the counters sent with the stat name
+// sum to expected.
+func (s *Statter) AssertCounter(t testing.TB, name string, expected int64) {
+	t.Helper()
+	if n := s.Count(name); n != expected {
+		t.Errorf("counter %q got %d expected %d", name, n, expected)
+	}
+}
+
+// AssertGauge fails the test unless the gauge with the stat name was sent,
+// and has the expected value.
+func (s *Statter) AssertGauge(t testing.TB, name string, expected float64) {
+	t.Helper()
+	v, ok := s.GaugeValue(name)
+	if !ok {
+		t.Errorf("gauge %q was not sent", name)
+	} else if v != expected {
+		t.Errorf("gauge %q got %v expected %v", name, v, expected)
+	}
+}
+
+// AssertSent fails the test unless a metric with the stat name was sent.
+func (s *Statter) AssertSent(t testing.TB, name string) {
+	t.Helper()
+	if len(s.Find(name)) == 0 {
+		t.Errorf("metric %q was not sent", name)
+	}
+}
+
+// AssertNotSent fails the test if a metric with the stat name was sent.
+func (s *Statter) AssertNotSent(t testing.TB, name string) {
+	t.Helper()
+	if found := s.Find(name); len(found) > 0 {
+		t.Errorf("metric %q was sent %d times", name, len(found))
+	}
+}
+
+// parseLine parses a line into its metrics, of which there are several in
+// the multi-value format of TimingMulti. Events, service checks, and lines
+// that are not metrics, are skipped.
+func parseLine(line string) []Metric {
+	if line == "" || strings.HasPrefix(line, "_e{") || strings.HasPrefix(line, "_sc|") {
+		return nil
+	}
+
+	var tags []statsd.Tag
+	if i := strings.Index(line, "|#"); i >= 0 {
+		for _, t := range strings.Split(line[i+2:], ",") {
+			k, v, _ := strings.Cut(t, ":")
+			tags = append(tags, statsd.Tag{Key: k, Value: v})
+		}
+		line = line[:i]
+	}
+
+	name, values, ok := strings.Cut(line, ":")
+	if !ok {
+		return nil
+	}
+	if i := strings.IndexByte(name, ','); i >= 0 {
+		for _, t := range strings.Split(name[i+1:], ",") {
+			k, v, _ := strings.Cut(t, "=")
+			tags = append(tags, statsd.Tag{Key: k, Value: v})
+		}
+		name = name[:i]
+	}
+
+	var metrics []Metric
+	for _, value := range strings.Split(values, ":") {
+		fields := strings.Split(value, "|")
+		if len(fields) < 2 {
+			continue
+		}
+		m := Metric{Name: name, Value: fields[0], Type: fields[1], Rate: 1, Tags: tags}
+		for _, f := range fields[2:] {
+			if strings.HasPrefix(f, "@") {
+				if r, err := strconv.ParseFloat(f[1:], 32); err == nil {
+					m.Rate = float32(r)
+				}
+			}
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics
+}
@@ -0,0 +1,65 @@
+package statsdtest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cactus/go-statsd-client/statsd"
+)
+
+func TestStatter(t *testing.T) {
+	s, err := New(statsd.WithPrefix("test"), statsd.WithTags(statsd.Tag{Key: "env", Value: "prod"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	s.Inc("count", 2, 1.0)
+	s.Inc("count", 3, 1.0)
+	s.Gauge("gauge", 5, 1.0)
+	s.GaugeDelta("gauge", -2, 1.0)
+	s.TimingMulti("timing", []time.Duration{time.Millisecond, 2 * time.Millisecond}, 1.0)
+	s.Event("deploy", "v1")
+
+	s.AssertCounter(t, "test.count", 5)
+	s.AssertGauge(t, "test.gauge", 3)
+	s.AssertSent(t, "test.timing")
+	s.AssertNotSent(t, "test.other")
+
+	expected := []Metric{
+		{Name: "test.timing", Value: "1.00", Type: "ms", Rate: 1, Tags: []statsd.Tag{{Key: "env", Value: "prod"}}},
+		{Name: "test.timing", Value: "2.00", Type: "ms", Rate: 1, Tags: []statsd.Tag{{Key: "env", Value: "prod"}}},
+	}
+	if found := s.Find("test.timing"); !reflect.DeepEqual(found, expected) {
+		t.Fatalf("got %+v expected %+v", found, expected)
+	}
+
+	s.Reset()
+	if metrics := s.Metrics(); len(metrics) != 0 {
+		t.Fatalf("got %+v expected no metrics after Reset", metrics)
+	}
+}
+
+var parseLineTests = []struct {
+	Line     string
+	Expected []Metric
+}{
+	{"a.b:1|c", []Metric{{Name: "a.b", Value: "1", Type: "c", Rate: 1}}},
+	{"a:+5|g|@0.5", []Metric{{Name: "a", Value: "+5", Type: "g", Rate: 0.5}}},
+	{"a:1|c|@0.1|#k:v,bare", []Metric{{Name: "a", Value: "1", Type: "c", Rate: 0.1,
+		Tags: []statsd.Tag{{Key: "k", Value: "v"}, {Key: "bare"}}}}},
+	{"a,k=v:1|ms", []Metric{{Name: "a", Value: "1", Type: "ms", Rate: 1,
+		Tags: []statsd.Tag{{Key: "k", Value: "v"}}}}},
+	{"_e{1,1}:a|b", nil},
+	{"_sc|check|0", nil},
+	{"garbage", nil},
+}
+
+func TestParseLine(t *testing.T) {
+	for _, tt := range parseLineTests {
+		if metrics := parseLine(tt.Line); !reflect.DeepEqual(metrics, tt.Expected) {
+			t.Fatalf("%q got %+v expected %+v", tt.Line, metrics, tt.Expected)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+//go:build !windows && !plan9
+
+package statsd
+
+import (
+	"log/syslog"
+	"sync"
+)
+
+// SyslogSender provides a send interface to syslog, writing each payload as
+// a message at INFO priority, such as to route metrics through existing log
+// aggregation for auditing, or where UDP egress is blocked.
+type SyslogSender struct {
+	w *syslog.Writer
+	// guards closed
+	mx     sync.RWMutex
+	closed bool
+}
+
+// Send writes the data to syslog as a single message. A payload holding
+// several metrics is not split.
+func (s *SyslogSender) Send(data []byte) (int, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	if s.closed {
+		return 0, ErrClosed
+	}
+
+	if err := s.w.Info(string(data)); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Close closes the connection to syslog. Later calls return nil, and Send
+// returns ErrClosed once closed.
+func (s *SyslogSender) Close() error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.w.Close()
+}
+
+// Returns a new SyslogSender, and an error.
+//
+// tag is the syslog tag of the messages, or the program name if "". Messages
+// are sent to the local syslog server with the USER facility. NewSyslogSender
+// returns an error on platforms without syslog.
+func NewSyslogSender(tag string) (Sender, error) {
+	w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_USER, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &SyslogSender{w: w}, nil
+}
+
+// newSyslogSenderAddr returns a SyslogSender sending to the syslog server at
+// raddr on network, as for syslog.Dial.
+func newSyslogSenderAddr(network, raddr, tag string) (Sender, error) {
+	w, err := syslog.Dial(network, raddr, syslog.LOG_INFO|syslog.LOG_USER, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &SyslogSender{w: w}, nil
+}
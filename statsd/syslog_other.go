@@ -0,0 +1,10 @@
+//go:build windows || plan9
+
+package statsd
+
+import "errors"
+
+// Returns an error, as syslog is not supported on this platform.
+func NewSyslogSender(tag string) (Sender, error) {
+	return nil, errors.New("statsd: syslog is not supported on this platform")
+}
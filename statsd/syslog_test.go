@@ -0,0 +1,61 @@
+//go:build !windows && !plan9
+
+package statsd
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogSender(t *testing.T) {
+	dir, err := os.MkdirTemp("", "statsd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "syslog.sock")
+	l, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	l.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+
+	s, err := newSyslogSenderAddr("unixgram", path, "statsd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "test.count:1|c"
+	_, err = s.Send([]byte(expected))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := make([]byte, 256)
+	n, _, err := l.ReadFrom(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// <14> is the USER facility at INFO priority
+	msg := string(data[:n])
+	if !strings.HasPrefix(msg, "<14>") || !strings.Contains(msg, "statsd") || !strings.HasSuffix(msg, expected+"\n") {
+		t.Fatalf("got '%s' expected an INFO message of '%s'", msg, expected)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("second Close got error '%v' expected nil", err)
+	}
+	if _, err := s.Send([]byte(expected)); !errors.Is(err, ErrClosed) {
+		t.Fatalf("got error '%v' expected '%v'", err, ErrClosed)
+	}
+}
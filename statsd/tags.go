@@ -0,0 +1,78 @@
+package statsd
+
+import "strings"
+
+// Tag is a DogStatsD style metric tag.
+// Value may be "" for a bare tag with only a key.
+type Tag struct {
+	Key   string
+	Value string
+}
+
+// TagFormat selects how tags are serialized in metrics.
+type TagFormat int
+
+const (
+	// TagFormatDatadog appends tags after the metric type, DogStatsD style,
+	// as "stat:1|c|#key1:value1,key2:value2". It is the default.
+	TagFormatDatadog TagFormat = iota
+	// TagFormatInflux adds tags to the stat name, as expected by the InfluxDB
+	// statsd parser of Telegraf, as "stat,key1=value1,key2=value2:1|c". Tags
+	// without a value are omitted, as the format has no bare tags.
+	TagFormatInflux
+)
+
+// tagReplacer strips the characters that delimit tags and metric segments.
+var tagReplacer = strings.NewReplacer(",", "", "|", "")
+
+// influxTagReplacer additionally strips the characters delimiting the key,
+// value and metric value in the InfluxDB format.
+var influxTagReplacer = strings.NewReplacer(",", "", "|", "", "=", "", ":", "", " ", "")
+
+// formatTags serializes tags, in order, in the given format: as
+// "key1:value1,key2:value2" for TagFormatDatadog, and as
+// ",key1=value1,key2=value2" for TagFormatInflux.
+func formatTags(tags []Tag, format TagFormat) string {
+	if format == TagFormatInflux {
+		var b strings.Builder
+		for _, t := range tags {
+			if t.Value == "" {
+				continue
+			}
+			b.WriteByte(',')
+			b.WriteString(influxTagReplacer.Replace(t.Key))
+			b.WriteByte('=')
+			b.WriteString(influxTagReplacer.Replace(t.Value))
+		}
+		return b.String()
+	}
+
+	parts := make([]string, 0, len(tags))
+	for _, t := range tags {
+		k := tagReplacer.Replace(t.Key)
+		if t.Value == "" {
+			parts = append(parts, k)
+			continue
+		}
+		parts = append(parts, k+":"+tagReplacer.Replace(t.Value))
+	}
+	return strings.Join(parts, ",")
+}
+
+// mergeTags returns a new slice of tags followed by extra, where a tag in
+// extra with the same key as an earlier tag replaces its value in place.
+func mergeTags(tags []Tag, extra []Tag) []Tag {
+	merged := make([]Tag, 0, len(tags)+len(extra))
+	merged = append(merged, tags...)
+next:
+	for _, t := range extra {
+		for i := range merged {
+			if merged[i].Key == t.Key {
+				merged[i].Value = t.Value
+				continue next
+			}
+		}
+		merged = append(merged, t)
+	}
+	return merged
+}
@@ -0,0 +1,172 @@
+package statsd
+
+import (
+	"bytes"
+	"testing"
+)
+
+var statsdTagTests = []struct {
+	Tags     []Tag
+	Rate     float32
+	Expected string
+}{
+	{[]Tag{{"env", "prod"}}, 1.0, "test.count:1|c|#env:prod"},
+	{[]Tag{{"env", "prod"}, {"canary", ""}}, 1.0, "test.count:1|c|#env:prod,canary"},
+	{[]Tag{{"b", "2"}, {"a", "1"}}, 1.0, "test.count:1|c|#b:2,a:1"},
+	{[]Tag{{"env", "pr,o|d"}}, 1.0, "test.count:1|c|#env:prod"},
+	{[]Tag{{"env", "prod"}}, 0.999999, "test.count:1|c|@0.999999|#env:prod"},
+}
+
+func TestClientWithTags(t *testing.T) {
+	l, err := newUDPListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	c, err := NewClient(l.LocalAddr().String(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for _, tt := range statsdTagTests {
+		err := c.WithTags(tt.Tags...).Inc("count", 1, tt.Rate)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data := make([]byte, 128)
+		_, _, err = l.ReadFrom(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data = bytes.TrimRight(data, "\x00")
+		if bytes.Equal(data, []byte(tt.Expected)) != true {
+			t.Fatalf("got '%s' expected '%s'", data, tt.Expected)
+		}
+	}
+}
+
+func TestClientWithTagsAppends(t *testing.T) {
+	l, err := newUDPListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	c, err := NewClient(l.LocalAddr().String(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	tc := c.WithTags(Tag{"a", "1"})
+	err = tc.WithTags(Tag{"b", "2"}).Inc("count", 1, 1.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := make([]byte, 128)
+	_, _, err = l.ReadFrom(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "test.count:1|c|#a:1,b:2"
+	data = bytes.TrimRight(data, "\x00")
+	if bytes.Equal(data, []byte(expected)) != true {
+		t.Fatalf("got '%s' expected '%s'", data, expected)
+	}
+}
+
+func TestClientConstantTags(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(
+		WithSender(rs),
+		WithTags(Tag{"service", "foo"}, Tag{"env", "prod"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Inc("count", 1, 1.0)
+	c.WithTags(Tag{"route", "home"}).Inc("count", 1, 1.0)
+	c.WithTags(Tag{"env", "dev"}, Tag{"service", "foo"}).Inc("count", 1, 1.0)
+
+	expected := []string{
+		"count:1|c|#service:foo,env:prod",
+		"count:1|c|#service:foo,env:prod,route:home",
+		"count:1|c|#service:foo,env:dev",
+	}
+	sent := rs.GetSent()
+	if len(sent) != len(expected) {
+		t.Fatalf("got %d payloads expected %d", len(sent), len(expected))
+	}
+	for i, e := range expected {
+		if string(sent[i]) != e {
+			t.Fatalf("got '%s' expected '%s'", sent[i], e)
+		}
+	}
+}
+
+var statsdTagFormatTests = []struct {
+	Format   TagFormat
+	Tags     []Tag
+	Expected string
+}{
+	{TagFormatDatadog, []Tag{{"env", "prod"}, {"canary", ""}}, "test.count:1|c|#env:prod,canary"},
+	{TagFormatInflux, []Tag{{"env", "prod"}, {"dc", "us1"}}, "test.count,env=prod,dc=us1:1|c"},
+	{TagFormatInflux, []Tag{{"env", "prod"}, {"canary", ""}}, "test.count,env=prod:1|c"},
+	{TagFormatInflux, []Tag{{"e=n:v", "pr,o d"}}, "test.count,env=prod:1|c"},
+	{TagFormatInflux, nil, "test.count:1|c"},
+}
+
+func TestClientTagFormat(t *testing.T) {
+	for _, tt := range statsdTagFormatTests {
+		rs := NewRecordingSender()
+		c, err := NewClientWithOptions(
+			WithSender(rs),
+			WithPrefix("test"),
+			WithTagFormat(tt.Format),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = c.WithTags(tt.Tags...).Inc("count", 1, 1.0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sent := rs.GetSent()
+		if len(sent) != 1 || string(sent[0]) != tt.Expected {
+			t.Fatalf("got '%s' expected '%s'", sent, tt.Expected)
+		}
+		c.Close()
+	}
+}
+
+func TestClientTagFormatInfluxRaw(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(
+		WithSender(rs),
+		WithTagFormat(TagFormatInflux),
+		WithTags(Tag{"env", "prod"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	err = c.Raw("raw", "1|c", 1.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "raw,env=prod:1|c"
+	sent := rs.GetSent()
+	if len(sent) != 1 || string(sent[0]) != expected {
+		t.Fatalf("got '%s' expected '%s'", sent, expected)
+	}
+}
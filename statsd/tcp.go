@@ -0,0 +1,173 @@
+package statsd
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// TCPSender provides a stream socket send interface, for when reliable
+// delivery is preferred over latency.
+type TCPSender struct {
+	// underlying connection, replaced by Reconnect
+	c net.Conn
+	// address dialed by Reconnect
+	addr string
+	// serializes writes, so metrics are not interleaved on the stream
+	mx sync.Mutex
+	// guards c and closed, apart from mx so that Close and Reconnect do not
+	// wait for a blocked write
+	connMx sync.Mutex
+	closed bool
+}
+
+// Send sends the data to the server endpoint, terminated by a newline.
+// Partial writes are retried until all the data has been written, or an
+// error (such as a broken pipe) occurs.
+func (s *TCPSender) Send(data []byte) (int, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	n, err := s.write(s.conn(), data)
+	return n, closedErr(err)
+}
+
+// SendContext sends the data like Send, but gives up and returns ctx.Err()
+// if ctx is done before the write completes. The deadline of ctx, if any,
+// is applied with SetWriteDeadline.
+func (s *TCPSender) SendContext(ctx context.Context, data []byte) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	c := s.conn()
+	if d, ok := ctx.Deadline(); ok {
+		c.SetWriteDeadline(d)
+	}
+	defer c.SetWriteDeadline(time.Time{})
+
+	// unblock the write if ctx is cancelled
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			c.SetWriteDeadline(time.Unix(1, 0))
+		case <-done:
+		}
+	}()
+
+	n, err := s.write(c, data)
+	close(done)
+	<-stopped
+
+	if err != nil && ctx.Err() != nil {
+		return n, ctx.Err()
+	}
+	return n, closedErr(err)
+}
+
+// write writes data and a trailing newline to c.
+// Must be called with the mutex held.
+func (s *TCPSender) write(c net.Conn, data []byte) (int, error) {
+	buf := make([]byte, 0, len(data)+1)
+	buf = append(buf, data...)
+	buf = append(buf, '\n')
+
+	total := 0
+	for total < len(buf) {
+		n, err := c.Write(buf[total:])
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// conn returns the current connection.
+func (s *TCPSender) conn() net.Conn {
+	s.connMx.Lock()
+	defer s.connMx.Unlock()
+	return s.c
+}
+
+// RemoteAddr returns the address of the server connected to.
+func (s *TCPSender) RemoteAddr() net.Addr {
+	return s.conn().RemoteAddr()
+}
+
+// Reconnect dials the address again, and replaces the connection with the
+// new one, such as after the server restarted and the connection broke. The
+// old connection is closed, so a write blocked on it returns an error, and
+// data written to it but not yet received by the server may be lost. It
+// returns ErrClosed once closed.
+func (s *TCPSender) Reconnect() error {
+	c, err := net.Dial("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	s.connMx.Lock()
+	if s.closed {
+		s.connMx.Unlock()
+		c.Close()
+		return ErrClosed
+	}
+	old := s.c
+	s.c = c
+	s.connMx.Unlock()
+
+	closeConn(old)
+	return nil
+}
+
+// Ping dials the address with a new connection, which is then closed, to
+// check that the server accepts connections. It returns ErrClosed once
+// closed.
+func (s *TCPSender) Ping() error {
+	s.connMx.Lock()
+	closed := s.closed
+	s.connMx.Unlock()
+	if closed {
+		return ErrClosed
+	}
+
+	c, err := net.Dial("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	return c.Close()
+}
+
+// Closes TCPSender
+// Later calls return nil, and Send returns ErrClosed once closed.
+func (s *TCPSender) Close() error {
+	s.connMx.Lock()
+	s.closed = true
+	c := s.c
+	s.connMx.Unlock()
+	return closeConn(c)
+}
+
+// Returns a new TCPSender for sending to the supplied addresss.
+//
+// addr is a string of the format "hostname:port", and must be parsable by
+// net.ResolveTCPAddr.
+func NewTCPSender(addr string) (Sender, error) {
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	sender := &TCPSender{
+		c:    c,
+		addr: addr,
+	}
+
+	return sender, nil
+}
@@ -0,0 +1,193 @@
+package statsd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTCPSender(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	s, err := NewTCPSender(l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+
+	expected := []string{"test.count:1|c", "test.gauge:1|g"}
+	for _, e := range expected {
+		n, err := s.Send([]byte(e))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != len(e)+1 {
+			t.Fatalf("wrote %d bytes expected %d", n, len(e)+1)
+		}
+	}
+
+	r := bufio.NewReader(conn)
+	for _, e := range expected {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line != e+"\n" {
+			t.Fatalf("got '%s' expected '%s'", line, e+"\n")
+		}
+	}
+}
+
+func TestTCPSenderBrokenConn(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	s, err := NewTCPSender(l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	// the first writes may succeed before the reset is noticed
+	for i := 0; i < 100; i++ {
+		_, err = s.Send([]byte("test.count:1|c"))
+		if err != nil {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("expected an error sending to a closed connection")
+}
+
+func TestTCPSenderContext(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	s, err := NewTCPSender(l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// the server never reads, so writes eventually block
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	data := bytes.Repeat([]byte("x"), 64*1024)
+	for {
+		_, err = s.(*TCPSender).SendContext(ctx, data)
+		if err != nil {
+			break
+		}
+	}
+	if err != context.DeadlineExceeded {
+		t.Fatalf("got error '%v' expected '%v'", err, context.DeadlineExceeded)
+	}
+}
+
+func TestTCPSenderReconnect(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	s, err := NewTCPSender(l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	if err := s.(Reconnecter).Reconnect(); err != nil {
+		t.Fatal(err)
+	}
+	conn, err = l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+
+	expected := "test.count:1|c"
+	if _, err := s.Send([]byte(expected)); err != nil {
+		t.Fatal(err)
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != expected+"\n" {
+		t.Fatalf("got '%s' expected '%s'", line, expected+"\n")
+	}
+
+	s.Close()
+	if err := s.(Reconnecter).Reconnect(); err != ErrClosed {
+		t.Fatalf("got error '%v' expected '%v'", err, ErrClosed)
+	}
+}
+
+func TestTCPSenderPing(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewTCPSender(l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	c, err := NewClientWithOptions(WithSender(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.(*Client).Ping(); err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+	if err := c.(*Client).Ping(); err == nil {
+		t.Fatal("expected an error without a listening server")
+	}
+	c.Close()
+	if err := s.(Pinger).Ping(); err != ErrClosed {
+		t.Fatalf("got error '%v' expected '%v'", err, ErrClosed)
+	}
+}
@@ -0,0 +1,255 @@
+package statsd
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// TeeStatter is a Statter sending every metric to both a primary and a
+// secondary Statter, such as to send to statsd and also record into an
+// in-process aggregator. Unlike MultiSender, which copies the formatted
+// packets, each Statter formats, samples and prefixes metrics its own way.
+//
+// Errors from the primary are returned. Errors from the secondary are
+// logged with the standard logger, so that it cannot fail the primary.
+type TeeStatter struct {
+	primary   Statter
+	secondary Statter
+}
+
+// NewTeeStatter returns a Statter sending every metric to both primary and
+// secondary.
+func NewTeeStatter(primary, secondary Statter) Statter {
+	return &TeeStatter{primary: primary, secondary: secondary}
+}
+
+// tee returns err, from the primary, after logging serr, from the secondary.
+func tee(err, serr error) error {
+	if serr != nil {
+		log.Printf("statsd: tee secondary: %v", serr)
+	}
+	return err
+}
+
+// Inc calls Inc on both Statters.
+func (s *TeeStatter) Inc(stat string, value int64, rate float32) error {
+	return tee(s.primary.Inc(stat, value, rate), s.secondary.Inc(stat, value, rate))
+}
+
+// Dec calls Dec on both Statters.
+func (s *TeeStatter) Dec(stat string, value int64, rate float32) error {
+	return tee(s.primary.Dec(stat, value, rate), s.secondary.Dec(stat, value, rate))
+}
+
+// IncMany calls IncMany on both Statters.
+func (s *TeeStatter) IncMany(counts map[string]int64, rate float32) error {
+	return tee(s.primary.IncMany(counts, rate), s.secondary.IncMany(counts, rate))
+}
+
+// Gauge calls Gauge on both Statters.
+func (s *TeeStatter) Gauge(stat string, value int64, rate float32) error {
+	return tee(s.primary.Gauge(stat, value, rate), s.secondary.Gauge(stat, value, rate))
+}
+
+// GaugeDelta calls GaugeDelta on both Statters.
+func (s *TeeStatter) GaugeDelta(stat string, value int64, rate float32) error {
+	return tee(s.primary.GaugeDelta(stat, value, rate), s.secondary.GaugeDelta(stat, value, rate))
+}
+
+// GaugeFloat calls GaugeFloat on both Statters.
+func (s *TeeStatter) GaugeFloat(stat string, value float64, rate float32) error {
+	return tee(s.primary.GaugeFloat(stat, value, rate), s.secondary.GaugeFloat(stat, value, rate))
+}
+
+// GaugeUint64 calls GaugeUint64 on both Statters.
+func (s *TeeStatter) GaugeUint64(stat string, value uint64, rate float32) error {
+	return tee(s.primary.GaugeUint64(stat, value, rate), s.secondary.GaugeUint64(stat, value, rate))
+}
+
+// GaugeTime calls GaugeTime on both Statters.
+func (s *TeeStatter) GaugeTime(stat string, t time.Time, rate float32) error {
+	return tee(s.primary.GaugeTime(stat, t, rate), s.secondary.GaugeTime(stat, t, rate))
+}
+
+// GaugeTimeMillis calls GaugeTimeMillis on both Statters.
+func (s *TeeStatter) GaugeTimeMillis(stat string, t time.Time, rate float32) error {
+	return tee(s.primary.GaugeTimeMillis(stat, t, rate), s.secondary.GaugeTimeMillis(stat, t, rate))
+}
+
+// RateGauge calls RateGauge on both Statters.
+func (s *TeeStatter) RateGauge(stat string, count int64, window time.Duration, rate float32) error {
+	return tee(s.primary.RateGauge(stat, count, window, rate), s.secondary.RateGauge(stat, count, window, rate))
+}
+
+// GaugeDeltaFloat calls GaugeDeltaFloat on both Statters.
+func (s *TeeStatter) GaugeDeltaFloat(stat string, value float64, rate float32) error {
+	return tee(s.primary.GaugeDeltaFloat(stat, value, rate), s.secondary.GaugeDeltaFloat(stat, value, rate))
+}
+
+// Timing calls Timing on both Statters.
+func (s *TeeStatter) Timing(stat string, delta int64, rate float32) error {
+	return tee(s.primary.Timing(stat, delta, rate), s.secondary.Timing(stat, delta, rate))
+}
+
+// TimingDuration calls TimingDuration on both Statters.
+func (s *TeeStatter) TimingDuration(stat string, delta time.Duration, rate float32) error {
+	return tee(s.primary.TimingDuration(stat, delta, rate), s.secondary.TimingDuration(stat, delta, rate))
+}
+
+// SampledTiming calls SampledTiming on both Statters.
+func (s *TeeStatter) SampledTiming(stat string, delta time.Duration, rate float32) error {
+	return tee(s.primary.SampledTiming(stat, delta, rate), s.secondary.SampledTiming(stat, delta, rate))
+}
+
+// TimingGauge calls TimingGauge on both Statters.
+func (s *TeeStatter) TimingGauge(stat string, delta time.Duration, rate float32) error {
+	return tee(s.primary.TimingGauge(stat, delta, rate), s.secondary.TimingGauge(stat, delta, rate))
+}
+
+// TimingMulti calls TimingMulti on both Statters.
+func (s *TeeStatter) TimingMulti(stat string, deltas []time.Duration, rate float32) error {
+	return tee(s.primary.TimingMulti(stat, deltas, rate), s.secondary.TimingMulti(stat, deltas, rate))
+}
+
+// Set calls Set on both Statters.
+func (s *TeeStatter) Set(stat string, value string, rate float32) error {
+	return tee(s.primary.Set(stat, value, rate), s.secondary.Set(stat, value, rate))
+}
+
+// Histogram calls Histogram on both Statters.
+func (s *TeeStatter) Histogram(stat string, value int64, rate float32) error {
+	return tee(s.primary.Histogram(stat, value, rate), s.secondary.Histogram(stat, value, rate))
+}
+
+// HistogramFloat calls HistogramFloat on both Statters.
+func (s *TeeStatter) HistogramFloat(stat string, value float64, rate float32) error {
+	return tee(s.primary.HistogramFloat(stat, value, rate), s.secondary.HistogramFloat(stat, value, rate))
+}
+
+// Distribution calls Distribution on both Statters.
+func (s *TeeStatter) Distribution(stat string, value float64, rate float32) error {
+	return tee(s.primary.Distribution(stat, value, rate), s.secondary.Distribution(stat, value, rate))
+}
+
+// Meter calls Meter on both Statters.
+func (s *TeeStatter) Meter(stat string, value int64, rate float32) error {
+	return tee(s.primary.Meter(stat, value, rate), s.secondary.Meter(stat, value, rate))
+}
+
+// Custom calls Custom on both Statters.
+func (s *TeeStatter) Custom(stat string, value string, suffix string, rate float32) error {
+	return tee(s.primary.Custom(stat, value, suffix, rate), s.secondary.Custom(stat, value, suffix, rate))
+}
+
+// Raw calls Raw on both Statters.
+func (s *TeeStatter) Raw(stat string, value string, rate float32) error {
+	return tee(s.primary.Raw(stat, value, rate), s.secondary.Raw(stat, value, rate))
+}
+
+// RawBytes calls RawBytes on both Statters.
+func (s *TeeStatter) RawBytes(stat []byte, value []byte, rate float32) error {
+	return tee(s.primary.RawBytes(stat, value, rate), s.secondary.RawBytes(stat, value, rate))
+}
+
+// ServiceCheck calls ServiceCheck on both Statters.
+func (s *TeeStatter) ServiceCheck(name string, status int, tags ...Tag) error {
+	return tee(s.primary.ServiceCheck(name, status, tags...), s.secondary.ServiceCheck(name, status, tags...))
+}
+
+// Event calls Event on both Statters.
+func (s *TeeStatter) Event(title, text string, tags ...Tag) error {
+	return tee(s.primary.Event(title, text, tags...), s.secondary.Event(title, text, tags...))
+}
+
+// NewTiming returns a Timing that sends via both Statters.
+func (s *TeeStatter) NewTiming() Timing {
+	return StartTiming(s)
+}
+
+// Counter returns a Counter that sends via both Statters.
+func (s *TeeStatter) Counter(stat string, rate float32) Counter {
+	return NewCounter(s, stat, rate)
+}
+
+// BoundGauge returns a Gauge that sends via both Statters.
+func (s *TeeStatter) BoundGauge(stat string, rate float32) Gauge {
+	return NewGauge(s, stat, rate)
+}
+
+// Timer returns a Timer that sends via both Statters.
+func (s *TeeStatter) Timer(stat string, rate float32) Timer {
+	return NewTimer(s, stat, rate)
+}
+
+// Calls f, and submits its duration as a timing via both Statters.
+func (s *TeeStatter) Time(stat string, rate float32, f func()) error {
+	timing := s.NewTiming()
+	f()
+	return timing.Send(stat, rate)
+}
+
+// Returns a function that, if the goroutine is panicking, increments the
+// stat counter via both Statters and panics again.
+func (s *TeeStatter) Recover(stat string) func() {
+	return func() {
+		if r := recover(); r != nil {
+			s.Inc(stat, 1, 1.0)
+			panic(r)
+		}
+	}
+}
+
+// NewBatch returns a batch of the primary only, as a Batch sends to a single
+// client.
+func (s *TeeStatter) NewBatch() *Batch {
+	return s.primary.NewBatch()
+}
+
+// SetPrefix sets the prefix of both Statters.
+func (s *TeeStatter) SetPrefix(prefix string) {
+	s.primary.SetPrefix(prefix)
+	s.secondary.SetPrefix(prefix)
+}
+
+// WithTags returns a TeeStatter of both Statters with the tags added.
+func (s *TeeStatter) WithTags(tags ...Tag) Statter {
+	return &TeeStatter{primary: s.primary.WithTags(tags...), secondary: s.secondary.WithTags(tags...)}
+}
+
+// NewSubStatter returns a TeeStatter of sub-statters of both Statters.
+func (s *TeeStatter) NewSubStatter(prefix string) Statter {
+	return &TeeStatter{primary: s.primary.NewSubStatter(prefix), secondary: s.secondary.NewSubStatter(prefix)}
+}
+
+// WithContext returns a TeeStatter of both Statters bound to ctx.
+func (s *TeeStatter) WithContext(ctx context.Context) Statter {
+	return &TeeStatter{primary: s.primary.WithContext(ctx), secondary: s.secondary.WithContext(ctx)}
+}
+
+// Flush flushes both Statters.
+func (s *TeeStatter) Flush() error {
+	return tee(s.primary.Flush(), s.secondary.Flush())
+}
+
+// Enabled reports whether either Statter is enabled, as metrics are still
+// recorded by the other.
+func (s *TeeStatter) Enabled() bool {
+	return s.primary.Enabled() || s.secondary.Enabled()
+}
+
+// Stats returns the statistics of the primary.
+func (s *TeeStatter) Stats() ClientStats {
+	return s.primary.Stats()
+}
+
+// PublishExpvar publishes the statistics of the primary, as a name is only
+// published once.
+func (s *TeeStatter) PublishExpvar(name string) {
+	s.primary.PublishExpvar(name)
+}
+
+// Close closes both Statters.
+func (s *TeeStatter) Close() error {
+	return tee(s.primary.Close(), s.secondary.Close())
+}
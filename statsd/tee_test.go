@@ -0,0 +1,77 @@
+package statsd
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestTeeStatter(t *testing.T) {
+	prs, srs := NewRecordingSender(), NewRecordingSender()
+	primary, err := NewClientWithOptions(WithSender(prs), WithPrefix("primary"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	secondary, err := NewClientWithOptions(WithSender(srs), WithPrefix("secondary"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewTeeStatter(primary, secondary)
+	defer s.Close()
+
+	if err := s.Inc("count", 1, 1.0); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.NewSubStatter("sub").Gauge("gauge", 2, 1.0); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		Sender *RecordingSender
+		Prefix string
+	}{{prs, "primary"}, {srs, "secondary"}} {
+		expected := []string{tt.Prefix + ".count:1|c", tt.Prefix + ".sub.gauge:2|g"}
+		sent := tt.Sender.GetSent()
+		if len(sent) != len(expected) {
+			t.Fatalf("got '%s' expected '%s'", sent, expected)
+		}
+		for i, e := range expected {
+			if string(sent[i]) != e {
+				t.Fatalf("got '%s' expected '%s'", sent[i], e)
+			}
+		}
+	}
+}
+
+func TestTeeStatterErrors(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	primary, err := NewClientWithOptions(WithSender(NewRecordingSender()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer primary.Close()
+	secondary, err := NewClientWithOptions(WithSender(NewRecordingSender()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	secondary.Close()
+
+	// an error from the secondary is logged, not returned
+	if err := NewTeeStatter(primary, secondary).Inc("count", 1, 1.0); err != nil {
+		t.Fatalf("got error '%v' expected nil", err)
+	}
+	if !strings.Contains(buf.String(), ErrClosed.Error()) {
+		t.Fatalf("got log '%s' expected the secondary error", buf.String())
+	}
+
+	// an error from the primary is returned
+	if err := NewTeeStatter(secondary, primary).Inc("count", 1, 1.0); !errors.Is(err, ErrClosed) {
+		t.Fatalf("got error '%v' expected '%v'", err, ErrClosed)
+	}
+}
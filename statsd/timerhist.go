@@ -0,0 +1,138 @@
+package statsd
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// reservoirSize is the largest number of durations a TimerHistogram keeps
+// per interval.
+const reservoirSize = 1028
+
+// TimerHistogram collects durations in memory, and every interval sends a
+// summary of them as gauges, for computing percentiles in the client rather
+// than on the server, such as for a single busy endpoint. The minimum,
+// maximum and mean are exact, while the percentiles are computed from a
+// uniform sample of at most 1028 durations, to bound memory.
+type TimerHistogram struct {
+	statter Statter
+	stat    string
+
+	mx      sync.Mutex
+	samples []time.Duration
+	count   int64
+	sum     time.Duration
+	min     time.Duration
+	max     time.Duration
+	rng     *rand.Rand
+
+	closeOnce sync.Once
+	done      chan struct{}
+	stopped   chan struct{}
+}
+
+// Record adds d to the durations of the current interval.
+func (h *TimerHistogram) Record(d time.Duration) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	h.count++
+	h.sum += d
+	if h.count == 1 || d < h.min {
+		h.min = d
+	}
+	if h.count == 1 || d > h.max {
+		h.max = d
+	}
+
+	// reservoir sampling, so that every duration is kept with equal chance
+	if len(h.samples) < reservoirSize {
+		h.samples = append(h.samples, d)
+	} else if i := h.rng.Int63n(h.count); i < reservoirSize {
+		h.samples[i] = d
+	}
+}
+
+// Flush sends the summary of the durations recorded since the last flush, as
+// gauges of milliseconds named by suffixing the stat with ".min", ".max",
+// ".mean", ".p50", ".p90" and ".p99", and starts a new interval. Nothing is
+// sent if no durations were recorded.
+func (h *TimerHistogram) Flush() error {
+	h.mx.Lock()
+	samples, count, sum, min, max := h.samples, h.count, h.sum, h.min, h.max
+	h.samples = make([]time.Duration, 0, len(samples))
+	h.count, h.sum, h.min, h.max = 0, 0, 0, 0
+	h.mx.Unlock()
+
+	if count == 0 {
+		return nil
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+
+	return errors.Join(
+		h.statter.TimingGauge(h.stat+".min", min, 1.0),
+		h.statter.TimingGauge(h.stat+".max", max, 1.0),
+		h.statter.TimingGauge(h.stat+".mean", sum/time.Duration(count), 1.0),
+		h.statter.TimingGauge(h.stat+".p50", percentile(samples, 0.5), 1.0),
+		h.statter.TimingGauge(h.stat+".p90", percentile(samples, 0.9), 1.0),
+		h.statter.TimingGauge(h.stat+".p99", percentile(samples, 0.99), 1.0),
+	)
+}
+
+// percentile returns the nearest rank p percentile of sorted.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+// Close stops the periodic flushes, and flushes the remaining durations.
+// Later calls return nil.
+func (h *TimerHistogram) Close() error {
+	var err error
+	h.closeOnce.Do(func() {
+		close(h.done)
+		<-h.stopped
+		err = h.Flush()
+	})
+	return err
+}
+
+// Returns a new TimerHistogram sending the summary of stat via statter every
+// flushInterval, until closed.
+//
+// If flushInterval is 0, defaults to 10 seconds.
+func NewTimerHistogram(statter Statter, stat string, flushInterval time.Duration) *TimerHistogram {
+	if flushInterval <= 0 {
+		flushInterval = 10 * time.Second
+	}
+
+	h := &TimerHistogram{
+		statter: statter,
+		stat:    stat,
+		rng:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(h.stopped)
+		ticker := time.NewTicker(flushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				h.Flush()
+			case <-h.done:
+				return
+			}
+		}
+	}()
+	return h
+}
@@ -0,0 +1,82 @@
+package statsd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimerHistogram(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithPrefix("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	h := NewTimerHistogram(c, "lat", time.Hour)
+	for i := 1; i <= 100; i++ {
+		h.Record(time.Duration(i) * time.Millisecond)
+	}
+	if len(rs.GetSent()) != 0 {
+		t.Fatal("summary sent before Flush")
+	}
+
+	if err := h.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"test.lat.min:1.00|g",
+		"test.lat.max:100.00|g",
+		"test.lat.mean:50.50|g",
+		"test.lat.p50:50.00|g",
+		"test.lat.p90:90.00|g",
+		"test.lat.p99:99.00|g",
+	}
+	sent := rs.GetSent()
+	if len(sent) != len(expected) {
+		t.Fatalf("got '%s' expected '%s'", sent, expected)
+	}
+	for i, e := range expected {
+		if string(sent[i]) != e {
+			t.Fatalf("got '%s' expected '%s'", sent[i], e)
+		}
+	}
+
+	// a new interval starts after a flush
+	rs.Clear()
+	if err := h.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if sent := rs.GetSent(); len(sent) != 0 {
+		t.Fatalf("got '%s' expected nothing sent for an empty interval", sent)
+	}
+
+	// Close flushes what is left
+	h.Record(2 * time.Millisecond)
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if sent := rs.GetSent(); len(sent) != len(expected) || string(sent[0]) != "test.lat.min:2.00|g" {
+		t.Fatalf("got '%s' expected a summary of 2ms", sent)
+	}
+	if err := h.Close(); err != nil {
+		t.Fatalf("second Close got error '%v' expected nil", err)
+	}
+}
+
+func TestTimerHistogramBounded(t *testing.T) {
+	h := NewTimerHistogram(&NoopClient{}, "lat", time.Hour)
+	defer h.Close()
+
+	for i := 0; i < 10*reservoirSize; i++ {
+		h.Record(time.Duration(i))
+	}
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	if len(h.samples) != reservoirSize {
+		t.Fatalf("kept %d samples expected %d", len(h.samples), reservoirSize)
+	}
+	if h.count != 10*reservoirSize || h.max != time.Duration(10*reservoirSize-1) {
+		t.Fatalf("got count %d and max %d", h.count, h.max)
+	}
+}
@@ -0,0 +1,81 @@
+package statsd
+
+import "time"
+
+// Clock provides the current time, for measuring durations.
+type Clock interface {
+	Now() time.Time
+}
+
+// realClock is a Clock using the system time.
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+// ZeroTimingPolicy controls how a Client handles timings sent as zero, such
+// as "0|ms", including durations that round to zero at the precision sent.
+type ZeroTimingPolicy int
+
+const (
+	// ZeroTimingSend sends zero timings as is. This is the default.
+	ZeroTimingSend ZeroTimingPolicy = iota
+	// ZeroTimingDrop sends nothing for zero timings.
+	ZeroTimingDrop
+	// ZeroTimingClamp sends zero timings as 1ms.
+	ZeroTimingClamp
+)
+
+// zeroTiming applies the ZeroTimingPolicy of the client to the formatted
+// timing value, returning the value to send, or false to send nothing.
+func (s *Client) zeroTiming(value []byte) ([]byte, bool) {
+	if s == nil || s.zeroTimings == ZeroTimingSend || !isZero(value) {
+		return value, true
+	}
+	if s.zeroTimings == ZeroTimingDrop {
+		return value, false
+	}
+	return append(value[:0], '1'), true
+}
+
+// isZero reports whether the formatted number is zero, such as "0" or "0.00".
+func isZero(value []byte) bool {
+	for _, c := range value {
+		if c != '0' && c != '.' {
+			return false
+		}
+	}
+	return len(value) > 0
+}
+
+// Timing measures the time elapsed since it was created, and sends it as a
+// statsd timing type.
+type Timing struct {
+	start   time.Time
+	clock   Clock
+	statter Statter
+}
+
+// Send submits the time elapsed since the Timing was created.
+// stat is a string name for the metric.
+// rate is the sample rate (0.0 to 1.0).
+func (t Timing) Send(stat string, rate float32) error {
+	return t.statter.TimingDuration(stat, t.Elapsed(), rate)
+}
+
+// Elapsed returns the time elapsed since the Timing was created.
+func (t Timing) Elapsed() time.Duration {
+	return t.clock.Now().Sub(t.start)
+}
+
+// newTiming returns a Timing started now according to clock, that sends via
+// statter.
+func newTiming(statter Statter, clock Clock) Timing {
+	return Timing{start: clock.Now(), clock: clock, statter: statter}
+}
+
+// StartTiming returns a Timing started now, that sends via statter. It is
+// for Statter implementations that wrap another Statter, so that their
+// NewTiming sends through the wrapper.
+func StartTiming(statter Statter) Timing {
+	return newTiming(statter, realClock{})
+}
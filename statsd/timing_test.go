@@ -0,0 +1,287 @@
+package statsd
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+var timingRe = regexp.MustCompile(`^test\.timing:[0-9]+\.[0-9]{2}\|ms$`)
+
+func TestClientNewTiming(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithPrefix("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	timing := c.NewTiming()
+	time.Sleep(2 * time.Millisecond)
+	err = timing.Send("timing", 1.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent := rs.GetSent()
+	if len(sent) != 1 || !timingRe.Match(sent[0]) {
+		t.Fatalf("got '%s' expected a timing", sent)
+	}
+}
+
+func TestClientTime(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithPrefix("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	called := false
+	err = c.Time("timing", 1.0, func() { called = true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !called {
+		t.Fatal("function was not called")
+	}
+
+	sent := rs.GetSent()
+	if len(sent) != 1 || !timingRe.Match(sent[0]) {
+		t.Fatalf("got '%s' expected a timing", sent)
+	}
+}
+
+func TestClientRecover(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithPrefix("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	func() {
+		defer c.Recover("ok")()
+	}()
+	if sent := rs.GetSent(); len(sent) != 0 {
+		t.Fatalf("got '%s' expected nothing sent without a panic", sent)
+	}
+
+	var r interface{}
+	func() {
+		defer func() { r = recover() }()
+		defer c.Recover("panic")()
+		panic("boom")
+	}()
+	if r != "boom" {
+		t.Fatalf("got panic '%v' expected 'boom'", r)
+	}
+	sent := rs.GetSent()
+	if len(sent) != 1 || string(sent[0]) != "test.panic:1|c" {
+		t.Fatalf("got '%s' expected 'test.panic:1|c'", sent)
+	}
+}
+
+// fakeClock is a Clock that only moves when advanced.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func TestClientWithClock(t *testing.T) {
+	rs := NewRecordingSender()
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	c, err := NewClientWithOptions(WithSender(rs), WithPrefix("test"), WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	err = c.Time("timing", 1.0, func() { clock.Advance(1500 * time.Microsecond) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timing := c.NewTiming()
+	clock.Advance(42 * time.Millisecond)
+	err = timing.Send("timing", 1.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"test.timing:1.50|ms", "test.timing:42.00|ms"}
+	sent := rs.GetSent()
+	if len(sent) != len(expected) {
+		t.Fatalf("got %d payloads expected %d", len(sent), len(expected))
+	}
+	for i, e := range expected {
+		if string(sent[i]) != e {
+			t.Fatalf("got '%s' expected '%s'", sent[i], e)
+		}
+	}
+}
+
+var roundedTimingTests = []struct {
+	Delta    time.Duration
+	Expected string
+}{
+	{400 * time.Microsecond, "timing:0|ms"},
+	{500 * time.Microsecond, "timing:1|ms"},
+	{1500 * time.Microsecond, "timing:2|ms"},
+	{42 * time.Millisecond, "timing:42|ms"},
+}
+
+func TestClientRoundedTimings(t *testing.T) {
+	for _, tt := range roundedTimingTests {
+		rs := NewRecordingSender()
+		c, err := NewClientWithOptions(WithSender(rs), WithRoundedTimings())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = c.TimingDuration("timing", tt.Delta, 1.0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sent := rs.GetSent()
+		if len(sent) != 1 || string(sent[0]) != tt.Expected {
+			t.Fatalf("got '%s' expected '%s'", sent, tt.Expected)
+		}
+	}
+}
+
+var timingPrecisionTests = []struct {
+	Precision int
+	Delta     time.Duration
+	Expected  string
+}{
+	{2, 123456 * time.Nanosecond, "timing:0.12|ms"},
+	{3, 123456 * time.Nanosecond, "timing:0.123|ms"},
+	{6, 123456 * time.Nanosecond, "timing:0.123456|ms"},
+	{9, 123456 * time.Nanosecond, "timing:0.123456000|ms"},
+	{0, 1500 * time.Microsecond, "timing:2|ms"},
+}
+
+func TestClientTimingPrecision(t *testing.T) {
+	for _, tt := range timingPrecisionTests {
+		rs := NewRecordingSender()
+		c, err := NewClientWithOptions(WithSender(rs), WithTimingPrecision(tt.Precision))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = c.TimingDuration("timing", tt.Delta, 1.0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sent := rs.GetSent()
+		if len(sent) != 1 || string(sent[0]) != tt.Expected {
+			t.Fatalf("precision %d got '%s' expected '%s'", tt.Precision, sent, tt.Expected)
+		}
+	}
+}
+
+func TestStartTiming(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithPrefix("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	err = StartTiming(c).Send("timing", 1.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent := rs.GetSent()
+	if len(sent) != 1 || !timingRe.Match(sent[0]) {
+		t.Fatalf("got '%s' expected a timing", sent)
+	}
+}
+
+func TestClientTimingMulti(t *testing.T) {
+	rs := NewRecordingSender()
+	c, err := NewClientWithOptions(WithSender(rs), WithPrefix("test"), WithRandSource(constSource(1<<62)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	deltas := []time.Duration{time.Millisecond, 2 * time.Millisecond, 3500 * time.Microsecond}
+	if err := c.TimingMulti("timing", deltas, 1.0); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.TimingMulti("timing", deltas[:2], 0.6); err != nil {
+		t.Fatal(err)
+	}
+	// sampled out, and no deltas, send nothing
+	if err := c.TimingMulti("timing", deltas, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.TimingMulti("timing", nil, 1.0); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"test.timing:1.00|ms:2.00|ms:3.50|ms",
+		"test.timing:1.00|ms|@0.6:2.00|ms|@0.6",
+	}
+	sent := rs.GetSent()
+	if len(sent) != len(expected) {
+		t.Fatalf("got '%s' expected '%s'", sent, expected)
+	}
+	for i, e := range expected {
+		if string(sent[i]) != e {
+			t.Fatalf("got '%s' expected '%s'", sent[i], e)
+		}
+	}
+}
+
+var zeroTimingTests = []struct {
+	Policy   ZeroTimingPolicy
+	Expected []string
+}{
+	{ZeroTimingSend, []string{"timing:0|ms", "timing:0.00|ms", "timing:0.00|ms", "timing:2.00|ms"}},
+	{ZeroTimingDrop, []string{"timing:2.00|ms"}},
+	{ZeroTimingClamp, []string{"timing:1|ms", "timing:1|ms", "timing:1|ms", "timing:2.00|ms"}},
+}
+
+func TestClientZeroTimings(t *testing.T) {
+	for _, tt := range zeroTimingTests {
+		rs := NewRecordingSender()
+		c, err := NewClientWithOptions(WithSender(rs), WithZeroTimings(tt.Policy))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// zero, and a duration that rounds to zero at the default precision
+		if err := c.Timing("timing", 0, 1.0); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.TimingDuration("timing", 0, 1.0); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.TimingDuration("timing", time.Microsecond, 1.0); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.TimingDuration("timing", 2*time.Millisecond, 1.0); err != nil {
+			t.Fatal(err)
+		}
+
+		sent := rs.GetSent()
+		if len(sent) != len(tt.Expected) {
+			t.Fatalf("policy %d got '%s' expected '%s'", tt.Policy, sent, tt.Expected)
+		}
+		for i, e := range tt.Expected {
+			if string(sent[i]) != e {
+				t.Fatalf("policy %d got '%s' expected '%s'", tt.Policy, sent[i], e)
+			}
+		}
+	}
+}
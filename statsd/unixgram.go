@@ -0,0 +1,123 @@
+package statsd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+)
+
+// UnixgramSender provides a unix datagram socket send interface.
+type UnixgramSender struct {
+	// underlying connection, replaced by Reconnect
+	c *net.UnixConn
+	// address of the socket
+	ra *net.UnixAddr
+	// guards c and closed
+	mx     sync.RWMutex
+	closed bool
+}
+
+// Send sends the data to the server endpoint as a single datagram.
+func (s *UnixgramSender) Send(data []byte) (int, error) {
+	n, err := s.conn().Write(data)
+	if err != nil {
+		return 0, closedErr(err)
+	}
+	if n == 0 {
+		return n, errors.New("Wrote no bytes")
+	}
+	return n, nil
+}
+
+// conn returns the current connection.
+func (s *UnixgramSender) conn() *net.UnixConn {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	return s.c
+}
+
+// RemoteAddr returns the address of the socket connected to.
+func (s *UnixgramSender) RemoteAddr() net.Addr {
+	return s.conn().RemoteAddr()
+}
+
+// Reconnect connects to the socket again, and replaces the connection with
+// the new one, such as after the server restarted and recreated the socket
+// file. It returns ErrClosed once closed.
+func (s *UnixgramSender) Reconnect() error {
+	c, err := net.DialUnix("unixgram", nil, s.ra)
+	if err != nil {
+		return err
+	}
+
+	s.mx.Lock()
+	if s.closed {
+		s.mx.Unlock()
+		c.Close()
+		return ErrClosed
+	}
+	old := s.c
+	s.c = c
+	s.mx.Unlock()
+
+	closeConn(old)
+	return nil
+}
+
+// Ping connects to the socket with a new connection, which is then closed,
+// to check that the socket exists and its server is listening. It returns
+// ErrClosed once closed.
+func (s *UnixgramSender) Ping() error {
+	s.mx.RLock()
+	closed := s.closed
+	s.mx.RUnlock()
+	if closed {
+		return ErrClosed
+	}
+
+	c, err := net.DialUnix("unixgram", nil, s.ra)
+	if err != nil {
+		return err
+	}
+	return c.Close()
+}
+
+// Closes UnixgramSender
+// The socket file itself is left in place. Later calls return nil, and Send
+// returns ErrClosed once closed.
+func (s *UnixgramSender) Close() error {
+	s.mx.Lock()
+	s.closed = true
+	c := s.c
+	s.mx.Unlock()
+	return closeConn(c)
+}
+
+// Returns a new UnixgramSender for sending to the unix datagram socket at
+// the supplied path.
+//
+// path is the filesystem path of the socket, such as "/var/run/statsd.sock".
+func NewUnixgramSender(path string) (Sender, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("statsd unix socket %q not available: %w", path, err)
+	}
+
+	ra, err := net.ResolveUnixAddr("unixgram", path)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := net.DialUnix("unixgram", nil, ra)
+	if err != nil {
+		return nil, err
+	}
+
+	sender := &UnixgramSender{
+		c:  c,
+		ra: ra,
+	}
+
+	return sender, nil
+}
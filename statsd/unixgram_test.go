@@ -0,0 +1,111 @@
+package statsd
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUnixgramSender(t *testing.T) {
+	dir, err := os.MkdirTemp("", "statsd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "statsd.sock")
+	l, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	l.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+
+	s, err := NewUnixgramSender(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "test.count:1|c"
+	_, err = s.Send([]byte(expected))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := make([]byte, 128)
+	n, _, err := l.ReadFrom(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data[:n]) != expected {
+		t.Fatalf("got '%s' expected '%s'", data[:n], expected)
+	}
+
+	err = s.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal("socket file removed by Close:", err)
+	}
+}
+
+func TestUnixgramSenderMissingPath(t *testing.T) {
+	_, err := NewUnixgramSender("/nonexistent/statsd.sock")
+	if err == nil {
+		t.Fatal("expected an error for a missing socket path")
+	}
+	if !os.IsNotExist(errors.Unwrap(err)) {
+		t.Fatalf("expected a not-exist error, got %v", err)
+	}
+}
+
+func TestUnixgramSenderReconnect(t *testing.T) {
+	dir, err := os.MkdirTemp("", "statsd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "statsd.sock")
+	addr := &net.UnixAddr{Name: path, Net: "unixgram"}
+	l, err := net.ListenUnixgram("unixgram", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewUnixgramSender(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// the server restarts, recreating the socket file
+	l.Close()
+	os.Remove(path)
+	l, err = net.ListenUnixgram("unixgram", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	l.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+
+	if err := s.(Reconnecter).Reconnect(); err != nil {
+		t.Fatal(err)
+	}
+	expected := "test.count:1|c"
+	if _, err := s.Send([]byte(expected)); err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 128)
+	n, _, err := l.ReadFrom(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data[:n]) != expected {
+		t.Fatalf("got '%s' expected '%s'", data[:n], expected)
+	}
+}
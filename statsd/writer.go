@@ -0,0 +1,52 @@
+package statsd
+
+import (
+	"io"
+	"sync"
+)
+
+// WriterSender provides a send interface to an io.Writer, such as a file or
+// a pipe to another process, for custom transports and for testing.
+type WriterSender struct {
+	w io.Writer
+	// serializes writes, so metrics are not interleaved, and guards buf and
+	// closed
+	mx     sync.Mutex
+	buf    []byte
+	closed bool
+}
+
+// Send writes the data to the writer, terminated by a newline, in a single
+// Write call.
+func (s *WriterSender) Send(data []byte) (int, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.closed {
+		return 0, ErrClosed
+	}
+
+	s.buf = append(s.buf[:0], data...)
+	s.buf = append(s.buf, '\n')
+	return s.w.Write(s.buf)
+}
+
+// Close closes the writer if it is an io.Closer, and otherwise does nothing
+// to it. Later calls return nil, and Send returns ErrClosed once closed.
+func (s *WriterSender) Close() error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Returns a new WriterSender, writing each payload followed by a newline to
+// w.
+func NewWriterSender(w io.Writer) Sender {
+	return &WriterSender{w: w}
+}
@@ -0,0 +1,50 @@
+package statsd
+
+import (
+	"bytes"
+	"testing"
+)
+
+// closeBuffer is a bytes.Buffer that records being closed.
+type closeBuffer struct {
+	bytes.Buffer
+	closed int
+}
+
+func (b *closeBuffer) Close() error {
+	b.closed++
+	return nil
+}
+
+func TestWriterSender(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := NewClientWithOptions(WithSender(NewWriterSender(&buf)), WithPrefix("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Inc("count", 1, 1.0)
+	c.Gauge("gauge", 2, 1.0)
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "test.count:1|c\ntest.gauge:2|g\n"
+	if buf.String() != expected {
+		t.Fatalf("got '%s' expected '%s'", buf.String(), expected)
+	}
+}
+
+func TestWriterSenderClose(t *testing.T) {
+	w := &closeBuffer{}
+	s := NewWriterSender(w)
+
+	for i := 0; i < 2; i++ {
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if w.closed != 1 {
+		t.Fatalf("writer closed %d times expected 1", w.closed)
+	}
+}